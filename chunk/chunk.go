@@ -0,0 +1,430 @@
+package chunk
+
+import (
+	"fmt"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/region"
+	"reflect"
+	"sort"
+)
+
+// Data versions at which the chunk format changed.
+const (
+	// VersionNonSpanning is the first version, 20w17a, whose packed arrays do not
+	// span longs.
+	VersionNonSpanning = 2529
+
+	// VersionFlat is the first version, 21w43a, storing the chunk at the root rather
+	// than in a Level compound, with sections holding block and biome palettes.
+	VersionFlat = 2844
+)
+
+// DefaultBiome fills the biomes of sections added by SetBlock and SetBiome.
+const DefaultBiome = "minecraft:plains"
+
+// Chunk is the block and biome data of a chunk, decoded from its NBT. Keys the type
+// does not model are kept and written back unchanged by Encode.
+//
+// Both layouts are read: chunks since 1.18 hold their sections at the root, with a
+// block state and a biome palette each, while older chunks wrap everything in a Level
+// compound and keep their biomes as numeric IDs in Level.Biomes, which are left as
+// they are.
+type Chunk struct {
+	// X and Z are the coordinates of the chunk in the world.
+	X, Z int
+
+	DataVersion int32
+
+	sections []*Section
+
+	// raw is the whole chunk and level the compound holding its data, raw itself or
+	// the Level compound of older chunks.
+	raw   map[string]interface{}
+	level map[string]interface{}
+}
+
+// Section is a 16x16x16 part of a chunk.
+type Section struct {
+	// Y is the vertical position of the section, the block Y coordinate divided by 16.
+	Y int
+
+	// palette is nil for sections holding only light, which chunks store above and
+	// below the blocks, and biomes is nil as well for the ones since 1.18.
+	palette []BlockState
+	blocks  container
+
+	biomes    []string
+	biomeData container
+
+	raw    map[string]interface{}
+	legacy bool
+}
+
+// Read reads and decodes the chunk at x, z of a region file.
+func Read(f *region.File, x, z int) (*Chunk, error) {
+	data, err := f.ReadChunk(x, z)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Write encodes the chunk and stores it in a region file, at the chunk coordinates.
+func (c *Chunk) Write(f *region.File) error {
+	return f.WriteChunk(c.X, c.Z, c.Encode(), region.CompressionZlib)
+}
+
+// Decode decodes the uncompressed NBT data of a chunk.
+func Decode(data []byte) (*Chunk, error) {
+	var raw map[string]interface{}
+	if _, err := nbt.Unmarshal(data, reflect.ValueOf(&raw).Elem()); err != nil {
+		return nil, err
+	}
+
+	c := &Chunk{raw: raw, level: raw}
+	if level, ok := raw["Level"].(map[string]interface{}); ok {
+		c.level = level
+	}
+	c.DataVersion, _ = raw["DataVersion"].(int32)
+	x, _ := c.level["xPos"].(int32)
+	z, _ := c.level["zPos"].(int32)
+	c.X, c.Z = int(x), int(z)
+
+	sections, _ := c.level[c.sectionsKey()].([]interface{})
+	for _, tree := range sections {
+		m, ok := tree.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("chunk %d,%d: section is not a compound", c.X, c.Z)
+		}
+		s, err := c.decodeSection(m)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d,%d: section %d: %w", c.X, c.Z, s.Y, err)
+		}
+		c.sections = append(c.sections, s)
+	}
+	return c, nil
+}
+
+// Legacy reports whether the chunk uses the layout of versions before 1.18, with its
+// data in a Level compound.
+func (c *Chunk) Legacy() bool {
+	_, ok := c.raw["Level"]
+	return ok
+}
+
+func (c *Chunk) sectionsKey() string {
+	if c.Legacy() {
+		return "Sections"
+	}
+	return "sections"
+}
+
+// newSection returns an empty section of the layout of the chunk.
+func (c *Chunk) newSection(y int) *Section {
+	s := &Section{Y: y, raw: make(map[string]interface{}), legacy: c.Legacy()}
+	if s.legacy {
+		s.blocks = container{size: 4096, minBits: 4, spanning: c.DataVersion < VersionNonSpanning}
+	} else {
+		s.blocks = container{size: 4096, minBits: 4, omitSingle: true}
+		s.biomeData = container{size: 64, omitSingle: true}
+	}
+	return s
+}
+
+func (c *Chunk) decodeSection(raw map[string]interface{}) (*Section, error) {
+	y, _ := raw["Y"].(byte)
+	s := c.newSection(int(int8(y)))
+	s.raw = raw
+
+	var err error
+	if s.legacy {
+		palette, ok := raw["Palette"].([]interface{})
+		if !ok {
+			return s, nil
+		}
+		s.palette = make([]BlockState, len(palette))
+		for i, entry := range palette {
+			s.palette[i] = blockStateFromTree(entry)
+		}
+		if data, ok := raw["BlockStates"].([]int64); ok {
+			if s.blocks.data, err = NewPackedArrayFrom(data, s.blocks.size, BitsFor(len(s.palette), s.blocks.minBits), s.blocks.spanning); err != nil {
+				return s, err
+			}
+		}
+		return s, s.blocks.check(len(s.palette))
+	}
+
+	blocks, ok := raw["block_states"].(map[string]interface{})
+	if !ok {
+		if _, ok = raw["biomes"]; !ok {
+			return s, nil
+		}
+	}
+	palette, _ := blocks["palette"].([]interface{})
+	s.palette = make([]BlockState, len(palette))
+	for i, entry := range palette {
+		s.palette[i] = blockStateFromTree(entry)
+	}
+	if len(s.palette) == 0 {
+		s.palette = []BlockState{Air}
+	}
+	if data, ok := blocks["data"].([]int64); ok {
+		if s.blocks.data, err = NewPackedArrayFrom(data, s.blocks.size, BitsFor(len(s.palette), s.blocks.minBits), false); err != nil {
+			return s, err
+		}
+	}
+	if err = s.blocks.check(len(s.palette)); err != nil {
+		return s, err
+	}
+
+	biomes, _ := raw["biomes"].(map[string]interface{})
+	biomePalette, _ := biomes["palette"].([]interface{})
+	for _, entry := range biomePalette {
+		name, _ := entry.(string)
+		s.biomes = append(s.biomes, name)
+	}
+	if len(s.biomes) == 0 {
+		s.biomes = []string{DefaultBiome}
+	}
+	if data, ok := biomes["data"].([]int64); ok {
+		if s.biomeData.data, err = NewPackedArrayFrom(data, s.biomeData.size, BitsFor(len(s.biomes), 0), false); err != nil {
+			return s, err
+		}
+	}
+	if err = s.biomeData.check(len(s.biomes)); err != nil {
+		return s, fmt.Errorf("biomes: %w", err)
+	}
+	return s, nil
+}
+
+// Encode encodes the chunk as uncompressed NBT. The palettes of the sections are
+// compacted first, dropping the entries no block or biome uses any more.
+func (c *Chunk) Encode() []byte {
+	sort.SliceStable(c.sections, func(i, j int) bool {
+		return c.sections[i].Y < c.sections[j].Y
+	})
+
+	sections := make([]interface{}, len(c.sections))
+	for i, s := range c.sections {
+		s.store()
+		sections[i] = s.raw
+	}
+	c.level[c.sectionsKey()] = sections
+	c.level["xPos"] = int32(c.X)
+	c.level["zPos"] = int32(c.Z)
+	c.raw["DataVersion"] = c.DataVersion
+	return nbt.Marshal("", c.raw)
+}
+
+// store compacts the palettes of the section and writes them to raw. Sections holding
+// only light are written as they were read.
+func (s *Section) store() {
+	s.raw["Y"] = byte(int8(s.Y))
+	if s.palette == nil {
+		return
+	}
+
+	kept := s.blocks.compact(len(s.palette))
+	palette := make([]BlockState, len(kept))
+	entries := make([]interface{}, len(kept))
+	for i, old := range kept {
+		palette[i] = s.palette[old]
+		entries[i] = palette[i].tree()
+	}
+	s.palette = palette
+
+	if s.legacy {
+		s.raw["Palette"] = entries
+		s.raw["BlockStates"] = s.blocks.data.Longs()
+		return
+	}
+
+	blocks, ok := s.raw["block_states"].(map[string]interface{})
+	if !ok {
+		blocks = make(map[string]interface{})
+		s.raw["block_states"] = blocks
+	}
+	blocks["palette"] = entries
+	storeData(blocks, s.blocks.data)
+
+	kept = s.biomeData.compact(len(s.biomes))
+	biomes := make([]string, len(kept))
+	names := make([]interface{}, len(kept))
+	for i, old := range kept {
+		biomes[i] = s.biomes[old]
+		names[i] = biomes[i]
+	}
+	s.biomes = biomes
+
+	biomeTree, ok := s.raw["biomes"].(map[string]interface{})
+	if !ok {
+		biomeTree = make(map[string]interface{})
+		s.raw["biomes"] = biomeTree
+	}
+	biomeTree["palette"] = names
+	storeData(biomeTree, s.biomeData.data)
+}
+
+func storeData(m map[string]interface{}, data *PackedArray) {
+	if data == nil {
+		delete(m, "data")
+	} else {
+		m["data"] = data.Longs()
+	}
+}
+
+// Sections returns the sections of the chunk, including the ones holding only light.
+func (c *Chunk) Sections() []*Section {
+	return c.sections
+}
+
+// Section returns the section at the vertical position y, or nil if the chunk has
+// none.
+func (c *Chunk) Section(y int) *Section {
+	for _, s := range c.sections {
+		if s.Y == y {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *Chunk) sectionOrNew(y int) *Section {
+	s := c.Section(y)
+	if s == nil {
+		s = c.newSection(y)
+		if !s.legacy {
+			s.initPalettes()
+		}
+		c.sections = append(c.sections, s)
+	}
+	return s
+}
+
+// BlockAt returns the block at x, y, z. The x and z coordinates may be given within
+// the chunk or the world, and y is the world height. Blocks outside the sections are
+// air.
+func (c *Chunk) BlockAt(x, y, z int) BlockState {
+	s := c.Section(y >> 4)
+	if s == nil {
+		return Air
+	}
+	return s.BlockAt(x&15, y&15, z&15)
+}
+
+// SetBlock sets the block at x, y, z, adding a section if the chunk has none at that
+// height. The coordinates are the ones of BlockAt.
+func (c *Chunk) SetBlock(x, y, z int, state BlockState) {
+	c.sectionOrNew(y>>4).SetBlock(x&15, y&15, z&15, state)
+}
+
+// BiomeAt returns the biome at x, y, z. The coordinates are the ones of BlockAt. It
+// returns false for chunks of versions before 1.18 and outside the sections.
+func (c *Chunk) BiomeAt(x, y, z int) (string, bool) {
+	s := c.Section(y >> 4)
+	if s == nil || s.legacy {
+		return "", false
+	}
+	return s.BiomeAt(x&15, y&15, z&15), true
+}
+
+// SetBiome sets the biome of the 4x4x4 cell holding x, y, z, adding a section if the
+// chunk has none at that height.
+func (c *Chunk) SetBiome(x, y, z int, biome string) error {
+	if c.Legacy() {
+		return fmt.Errorf("chunk %d,%d: biomes before 1.18 are not stored in palettes", c.X, c.Z)
+	}
+	c.sectionOrNew(y>>4).SetBiome(x&15, y&15, z&15, biome)
+	return nil
+}
+
+// initPalettes fills the palettes of a section holding only light with air and, since
+// 1.18, DefaultBiome.
+func (s *Section) initPalettes() {
+	s.palette = []BlockState{Air}
+	if !s.legacy {
+		s.biomes = []string{DefaultBiome}
+	}
+}
+
+// blockIndex returns the index of the block at x, y, z of a section.
+func blockIndex(x, y, z int) int {
+	return y<<8 | z<<4 | x
+}
+
+// Palette returns the block states the section refers to. It is empty for sections
+// holding only light.
+func (s *Section) Palette() []BlockState {
+	return s.palette
+}
+
+// BlockAt returns the block at x, y, z within the section.
+func (s *Section) BlockAt(x, y, z int) BlockState {
+	if s.palette == nil {
+		return Air
+	}
+	return s.palette[s.blocks.get(blockIndex(x, y, z))]
+}
+
+// SetBlock sets the block at x, y, z within the section, adding the state to the
+// palette if it is not there yet.
+func (s *Section) SetBlock(x, y, z int, state BlockState) {
+	if s.palette == nil {
+		s.initPalettes()
+	}
+
+	entry := s.paletteEntry(state)
+	s.blocks.set(blockIndex(x, y, z), entry, len(s.palette))
+}
+
+// paletteEntry returns the index of state in the palette, adding it if needed.
+func (s *Section) paletteEntry(state BlockState) int {
+	for i, p := range s.palette {
+		if p.Equal(state) {
+			return i
+		}
+	}
+	s.palette = append(s.palette, state)
+	return len(s.palette) - 1
+}
+
+// biomeIndex returns the index of the 4x4x4 cell holding x, y, z of a section.
+func biomeIndex(x, y, z int) int {
+	return y>>2<<4 | z>>2<<2 | x>>2
+}
+
+// BiomeAt returns the biome at x, y, z within the section. It is empty for sections
+// of versions before 1.18, and DefaultBiome for the ones holding only light.
+func (s *Section) BiomeAt(x, y, z int) string {
+	if s.legacy {
+		return ""
+	}
+	if s.biomes == nil {
+		return DefaultBiome
+	}
+	return s.biomes[s.biomeData.get(biomeIndex(x, y, z))]
+}
+
+// SetBiome sets the biome of the 4x4x4 cell holding x, y, z within the section. It
+// does nothing for sections of versions before 1.18.
+func (s *Section) SetBiome(x, y, z int, biome string) {
+	if s.legacy {
+		return
+	}
+	if s.palette == nil {
+		s.initPalettes()
+	}
+
+	entry := -1
+	for i, b := range s.biomes {
+		if b == biome {
+			entry = i
+			break
+		}
+	}
+	if entry < 0 {
+		entry = len(s.biomes)
+		s.biomes = append(s.biomes, biome)
+	}
+	s.biomeData.set(biomeIndex(x, y, z), entry, len(s.biomes))
+}
@@ -0,0 +1,407 @@
+package chunk
+
+import (
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/region"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var (
+	stone   = BlockState{Name: "minecraft:stone"}
+	dirt    = BlockState{Name: "minecraft:dirt"}
+	oakLogY = BlockState{Name: "minecraft:oak_log", Properties: map[string]string{"axis": "y"}}
+	oakLogX = BlockState{Name: "minecraft:oak_log", Properties: map[string]string{"axis": "x"}}
+)
+
+func decodeTree(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+
+	var tree map[string]interface{}
+	if _, err := nbt.Unmarshal(data, reflect.ValueOf(&tree).Elem()); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// flatChunk returns a 1.18 chunk with a section holding only light below the blocks,
+// a stone section at y -64 to -49 and a section at 0 to 15 holding stone below y 4 and
+// air above, in two biomes.
+func flatChunk() map[string]interface{} {
+	blocks := NewPackedArray(4096, 4, false)
+	for i := 0; i < 4*256; i++ {
+		blocks.Set(i, 1)
+	}
+	biomes := NewPackedArray(64, 1, false)
+	for i := 32; i < 64; i++ {
+		biomes.Set(i, 1)
+	}
+
+	return map[string]interface{}{
+		"DataVersion": int32(2975),
+		"xPos":        int32(3),
+		"zPos":        int32(-2),
+		"Status":      "full",
+		"sections": []interface{}{
+			map[string]interface{}{
+				"Y":        byte(0xfb),
+				"SkyLight": make([]byte, 2048),
+			},
+			map[string]interface{}{
+				"Y": byte(0xfc),
+				"block_states": map[string]interface{}{
+					"palette": []interface{}{stone.tree()},
+				},
+				"biomes": map[string]interface{}{
+					"palette": []interface{}{"minecraft:deep_dark"},
+				},
+				"SkyLight": make([]byte, 2048),
+			},
+			map[string]interface{}{
+				"Y": byte(0),
+				"block_states": map[string]interface{}{
+					"palette": []interface{}{Air.tree(), stone.tree()},
+					"data":    blocks.Longs(),
+				},
+				"biomes": map[string]interface{}{
+					"palette": []interface{}{"minecraft:plains", "minecraft:forest"},
+					"data":    biomes.Longs(),
+				},
+			},
+		},
+	}
+}
+
+// levelChunk returns a 1.15 chunk with a section at y 16 to 31 whose 17 palette
+// entries are packed at 5 bits spanning longs, and a section holding only light.
+func levelChunk() map[string]interface{} {
+	palette := []interface{}{Air.tree()}
+	for i := 1; i < 17; i++ {
+		palette = append(palette, BlockState{Name: "minecraft:wool", Properties: map[string]string{"color": string(rune('a' + i))}}.tree())
+	}
+	blocks := NewPackedArray(4096, 5, true)
+	for i := 0; i < 4096; i++ {
+		blocks.Set(i, i%17)
+	}
+
+	return map[string]interface{}{
+		"DataVersion": int32(2230),
+		"Level": map[string]interface{}{
+			"xPos":   int32(-1),
+			"zPos":   int32(7),
+			"Biomes": make([]int32, 1024),
+			"Sections": []interface{}{
+				map[string]interface{}{
+					"Y":        byte(0xff),
+					"SkyLight": make([]byte, 2048),
+				},
+				map[string]interface{}{
+					"Y":           byte(1),
+					"Palette":     palette,
+					"BlockStates": blocks.Longs(),
+					"BlockLight":  make([]byte, 2048),
+				},
+			},
+		},
+	}
+}
+
+func TestBlockAt(t *testing.T) {
+	flat, err := Decode(nbt.Marshal("", flatChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	level, err := Decode(nbt.Marshal("", levelChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		chunk   *Chunk
+		x, y, z int
+		want    string
+	}{
+		{name: "single entry palette", chunk: flat, x: 5, y: -60, z: 9, want: "minecraft:stone"},
+		{name: "packed", chunk: flat, x: 15, y: 3, z: 15, want: "minecraft:stone"},
+		{name: "above stone", chunk: flat, x: 0, y: 4, z: 0, want: "minecraft:air"},
+		{name: "world coordinates", chunk: flat, x: 3*16 + 2, y: 1, z: -2*16 + 2, want: "minecraft:stone"},
+		{name: "missing section", chunk: flat, x: 0, y: 100, z: 0, want: "minecraft:air"},
+		{name: "spanning", chunk: level, x: 3, y: 16, z: 0, want: "minecraft:wool[color=d]"},
+		{name: "entry split across longs", chunk: level, x: 12, y: 16, z: 0, want: "minecraft:wool[color=m]"},
+		{name: "later layer", chunk: level, x: 0, y: 17, z: 0, want: BlockState{Name: "minecraft:wool", Properties: map[string]string{"color": string(rune('a' + 256%17))}}.String()},
+		{name: "light only section", chunk: level, x: 0, y: -5, z: 0, want: "minecraft:air"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.chunk.BlockAt(tt.x, tt.y, tt.z).String(); got != tt.want {
+				t.Errorf("BlockAt(%d, %d, %d) = %s, want %s", tt.x, tt.y, tt.z, got, tt.want)
+			}
+		})
+	}
+
+	if flat.X != 3 || flat.Z != -2 || level.X != -1 || level.Z != 7 {
+		t.Errorf("chunk positions %d,%d and %d,%d", flat.X, flat.Z, level.X, level.Z)
+	}
+	if flat.Legacy() || !level.Legacy() {
+		t.Errorf("Legacy() = %v and %v", flat.Legacy(), level.Legacy())
+	}
+}
+
+func TestSetBlock(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk map[string]interface{}
+	}{
+		{name: "flat", chunk: flatChunk()},
+		{name: "level", chunk: levelChunk()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Decode(nbt.Marshal("", tt.chunk))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c.SetBlock(0, 0, 0, oakLogY)
+			c.SetBlock(1, 0, 0, oakLogX)
+			c.SetBlock(2, 0, 0, oakLogY)
+			c.SetBlock(7, 20, 7, dirt)
+			c.SetBlock(4, 300, 4, dirt)
+			c.SetBlock(4, -1000, 4, stone)
+
+			c, err = Decode(c.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[[3]int]BlockState{
+				{0, 0, 0}: oakLogY, {1, 0, 0}: oakLogX, {2, 0, 0}: oakLogY,
+				{7, 20, 7}: dirt, {4, 300, 4}: dirt, {4, -1000, 4}: stone,
+				{5, 300, 4}: Air,
+			}
+			for pos, state := range want {
+				if got := c.BlockAt(pos[0], pos[1], pos[2]); !got.Equal(state) {
+					t.Errorf("BlockAt(%v) = %s, want %s", pos, got, state)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeCompactsPalettes(t *testing.T) {
+	c, err := Decode(nbt.Marshal("", flatChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for y := 0; y < 4; y++ {
+		for z := 0; z < 16; z++ {
+			for x := 0; x < 16; x++ {
+				c.SetBlock(x, y, z, dirt)
+			}
+		}
+	}
+	c.SetBlock(0, -64, 0, dirt)
+	for y := 0; y < 16; y += 4 {
+		for z := 0; z < 16; z += 4 {
+			for x := 0; x < 16; x += 4 {
+				if err = c.SetBiome(x, y, z, "minecraft:forest"); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	tree := decodeTree(t, c.Encode())
+	sections := tree["sections"].([]interface{})
+
+	bottom := sections[1].(map[string]interface{})["block_states"].(map[string]interface{})
+	if palette := bottom["palette"].([]interface{}); len(palette) != 2 {
+		t.Errorf("bottom palette has %d entries, want 2", len(palette))
+	}
+	if data := bottom["data"].([]int64); len(data) != 256 {
+		t.Errorf("bottom data has %d longs, want 256", len(data))
+	}
+
+	blocks := sections[2].(map[string]interface{})["block_states"].(map[string]interface{})
+	if palette := blocks["palette"].([]interface{}); !reflect.DeepEqual(palette, []interface{}{Air.tree(), dirt.tree()}) {
+		t.Errorf("palette = %v, want air and dirt", palette)
+	}
+
+	biomes := sections[2].(map[string]interface{})["biomes"].(map[string]interface{})
+	if palette := biomes["palette"].([]interface{}); !reflect.DeepEqual(palette, []interface{}{"minecraft:forest"}) {
+		t.Errorf("biome palette = %v, want forest only", palette)
+	}
+	if _, ok := biomes["data"]; ok {
+		t.Errorf("biome data kept for a single entry palette")
+	}
+
+	for y := 0; y < 16; y++ {
+		for z := 0; z < 16; z++ {
+			for x := 0; x < 16; x++ {
+				c.SetBlock(x, y, z, Air)
+			}
+		}
+	}
+	tree = decodeTree(t, c.Encode())
+	blocks = tree["sections"].([]interface{})[2].(map[string]interface{})["block_states"].(map[string]interface{})
+	if _, ok := blocks["data"]; ok {
+		t.Errorf("block data kept for a section of air")
+	}
+}
+
+func TestBiomeAt(t *testing.T) {
+	c, err := Decode(nbt.Marshal("", flatChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		x, y, z int
+		want    string
+		ok      bool
+	}{
+		{x: 0, y: -64, z: 0, want: "minecraft:deep_dark", ok: true},
+		{x: 15, y: 7, z: 15, want: "minecraft:plains", ok: true},
+		{x: 0, y: 8, z: 0, want: "minecraft:forest", ok: true},
+		{x: 0, y: 16, z: 0},
+	}
+	for _, tt := range tests {
+		if got, ok := c.BiomeAt(tt.x, tt.y, tt.z); got != tt.want || ok != tt.ok {
+			t.Errorf("BiomeAt(%d, %d, %d) = %q, %v, want %q, %v", tt.x, tt.y, tt.z, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if err = c.SetBiome(1, 100, 1, "minecraft:desert"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.BiomeAt(0, 100, 0); got != "minecraft:desert" {
+		t.Errorf("BiomeAt in a new section = %q, want minecraft:desert", got)
+	}
+	if got, _ := c.BiomeAt(4, 100, 0); got != DefaultBiome {
+		t.Errorf("BiomeAt in a new section = %q, want %s", got, DefaultBiome)
+	}
+
+	level, err := Decode(nbt.Marshal("", levelChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := level.BiomeAt(0, 16, 0); ok {
+		t.Errorf("BiomeAt of a chunk before 1.18 returned a biome")
+	}
+	if err = level.SetBiome(0, 16, 0, "minecraft:desert"); err == nil {
+		t.Errorf("expected an error setting a biome of a chunk before 1.18")
+	}
+}
+
+func TestEncodeKeepsUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk map[string]interface{}
+	}{
+		{name: "flat", chunk: flatChunk()},
+		{name: "level", chunk: levelChunk()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := nbt.Marshal("", tt.chunk)
+			c, err := Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := decodeTree(t, c.Encode()), decodeTree(t, data); !reflect.DeepEqual(got, want) {
+				t.Errorf("encoded chunk differs:\n%s", nbt.FormatDiff(nbt.Diff(want, got, nbt.DiffOptions{})))
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	outOfPalette := func(length int, bits int, spanning bool) []int64 {
+		a := NewPackedArray(length, bits, spanning)
+		a.Set(length-1, 3)
+		return a.Longs()
+	}
+	flatSection := func(tree map[string]interface{}) map[string]interface{} {
+		return tree["sections"].([]interface{})[2].(map[string]interface{})
+	}
+
+	tests := []struct {
+		name   string
+		chunk  map[string]interface{}
+		damage func(tree map[string]interface{})
+	}{
+		{
+			name:  "short block state array",
+			chunk: flatChunk(),
+			damage: func(tree map[string]interface{}) {
+				flatSection(tree)["block_states"].(map[string]interface{})["data"] = make([]int64, 10)
+			},
+		},
+		{
+			name:  "block index out of the palette",
+			chunk: flatChunk(),
+			damage: func(tree map[string]interface{}) {
+				flatSection(tree)["block_states"].(map[string]interface{})["data"] = outOfPalette(4096, 4, false)
+			},
+		},
+		{
+			name:  "biome index out of the palette",
+			chunk: flatChunk(),
+			damage: func(tree map[string]interface{}) {
+				biomes := flatSection(tree)["biomes"].(map[string]interface{})
+				biomes["palette"] = []interface{}{"minecraft:plains", "minecraft:forest", "minecraft:desert"}
+				biomes["data"] = outOfPalette(64, 2, false)
+			},
+		},
+		{
+			name:  "block index out of the palette before 1.18",
+			chunk: levelChunk(),
+			damage: func(tree map[string]interface{}) {
+				section := tree["Level"].(map[string]interface{})["Sections"].([]interface{})[1].(map[string]interface{})
+				section["Palette"] = section["Palette"].([]interface{})[:2]
+				section["BlockStates"] = outOfPalette(4096, 4, true)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.damage(tt.chunk)
+			if _, err := Decode(nbt.Marshal("", tt.chunk)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.-1.mca")
+	f, err := region.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	c, err := Decode(nbt.Marshal("", flatChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBlock(1, 2, 3, oakLogX)
+	if err = c.Write(f); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Read(f, 3, -2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.BlockAt(1, 2, 3); !got.Equal(oakLogX) {
+		t.Errorf("BlockAt = %s, want %s", got, oakLogX)
+	}
+}
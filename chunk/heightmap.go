@@ -0,0 +1,173 @@
+package chunk
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Names of the heightmaps chunks store in their Heightmaps compound.
+const (
+	MotionBlocking         = "MOTION_BLOCKING"
+	MotionBlockingNoLeaves = "MOTION_BLOCKING_NO_LEAVES"
+	OceanFloor             = "OCEAN_FLOOR"
+	WorldSurface           = "WORLD_SURFACE"
+)
+
+// heightmapBits is the size of the entries of heightmaps, enough for the 384 blocks
+// of the overworld since 1.18.
+const heightmapBits = 9
+
+// Heightmap holds, for each column of a chunk, the height of the first block above
+// the highest block matching some predicate, such as the highest solid block.
+type Heightmap struct {
+	// MinY is the height of the bottom of the world, from which heights are stored.
+	MinY int
+
+	heights [256]int
+}
+
+// NewHeightmap returns a heightmap whose columns hold no matching block.
+func NewHeightmap(minY int) *Heightmap {
+	return &Heightmap{MinY: minY}
+}
+
+// DecodeHeightmap decodes a heightmap from the longs of its TAG_Long_Array. Entries
+// span longs in chunks older than 1.16.
+func DecodeHeightmap(data []int64, minY int, spanning bool) (*Heightmap, error) {
+	a, err := NewPackedArrayFrom(data, 256, heightmapBits, spanning)
+	if err != nil {
+		return nil, err
+	}
+	h := NewHeightmap(minY)
+	for i := range h.heights {
+		h.heights[i] = a.Get(i)
+	}
+	return h, nil
+}
+
+// Encode returns the longs of the TAG_Long_Array storing the heightmap.
+func (h *Heightmap) Encode(spanning bool) []int64 {
+	a := NewPackedArray(256, heightmapBits, spanning)
+	for i, height := range h.heights {
+		a.Set(i, height)
+	}
+	return a.Longs()
+}
+
+// Height returns the world Y of the first block above the highest matching block of
+// the column x, z, or MinY when no block matches.
+func (h *Heightmap) Height(x, z int) int {
+	return h.MinY + h.heights[(z&15)<<4|x&15]
+}
+
+// SetHeight sets the height of the column x, z.
+func (h *Heightmap) SetHeight(x, z, y int) {
+	h.heights[(z&15)<<4|x&15] = y - h.MinY
+}
+
+// MinY returns the height of the bottom of the chunk: the yPos entry of chunks since
+// 1.18, or else the bottom of their lowest section holding blocks, and 0 for older
+// chunks.
+func (c *Chunk) MinY() int {
+	if c.Legacy() {
+		return 0
+	}
+	if y, ok := c.level["yPos"].(int32); ok {
+		return int(y) * 16
+	}
+	minY, found := 0, false
+	for _, s := range c.sections {
+		if s.palette != nil && (!found || s.Y*16 < minY) {
+			minY, found = s.Y*16, true
+		}
+	}
+	return minY
+}
+
+// Heightmap decodes the heightmap of the given name, such as MotionBlocking. It
+// returns nil if the chunk has none.
+func (c *Chunk) Heightmap(name string) (*Heightmap, error) {
+	heightmaps, _ := c.level["Heightmaps"].(map[string]interface{})
+	data, ok := heightmaps[name].([]int64)
+	if !ok {
+		return nil, nil
+	}
+	h, err := DecodeHeightmap(data, c.MinY(), c.DataVersion < VersionNonSpanning)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %d,%d: heightmap %s: %w", c.X, c.Z, name, err)
+	}
+	return h, nil
+}
+
+// SetHeightmap stores the heightmap of the given name.
+func (c *Chunk) SetHeightmap(name string, h *Heightmap) {
+	heightmaps, ok := c.level["Heightmaps"].(map[string]interface{})
+	if !ok {
+		heightmaps = make(map[string]interface{})
+		c.level["Heightmaps"] = heightmaps
+	}
+	heightmaps[name] = h.Encode(c.DataVersion < VersionNonSpanning)
+}
+
+// ComputeHeightmap computes a heightmap from the blocks of the chunk, finding in each
+// column the highest block for which match returns true. Match is called once per
+// palette entry rather than once per block.
+//
+// Vanilla matches the blocks that are not air for WorldSurface, and the blocks that
+// block motion or hold fluid for MotionBlocking; after editing blocks, recompute the
+// heightmaps with the predicates of the game and store them with SetHeightmap.
+func (c *Chunk) ComputeHeightmap(match func(BlockState) bool) *Heightmap {
+	h := NewHeightmap(c.MinY())
+
+	sections := make([]*Section, 0, len(c.sections))
+	for _, s := range c.sections {
+		if s.palette != nil && s.Y*16 >= h.MinY {
+			sections = append(sections, s)
+		}
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].Y > sections[j].Y
+	})
+
+	remaining := 256
+	found := make([]bool, 256)
+	for _, s := range sections {
+		matches := make([]bool, len(s.palette))
+		matched := false
+		for i, state := range s.palette {
+			matches[i] = match(state)
+			matched = matched || matches[i]
+		}
+		if !matched {
+			continue
+		}
+
+		for column := 0; column < 256; column++ {
+			if found[column] {
+				continue
+			}
+			for y := 15; y >= 0; y-- {
+				if matches[s.blocks.get(y<<8|column)] {
+					h.heights[column] = s.Y*16 + y + 1 - h.MinY
+					found[column] = true
+					remaining--
+					break
+				}
+			}
+		}
+		if remaining == 0 {
+			break
+		}
+	}
+	return h
+}
+
+// NotAir matches every block but air, cave air and void air, like the WorldSurface
+// heightmap.
+func NotAir(state BlockState) bool {
+	switch state.Name {
+	case "minecraft:air", "minecraft:cave_air", "minecraft:void_air":
+		return false
+	}
+	return true
+}
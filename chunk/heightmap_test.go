@@ -0,0 +1,154 @@
+package chunk
+
+import (
+	"github.com/junglemc/nbt"
+	"testing"
+)
+
+// bruteHeightmap computes a heightmap one block at a time.
+func bruteHeightmap(c *Chunk, match func(BlockState) bool) *Heightmap {
+	h := NewHeightmap(c.MinY())
+	for z := 0; z < 16; z++ {
+		for x := 0; x < 16; x++ {
+			for y := h.MinY + 511; y >= h.MinY; y-- {
+				if match(c.BlockAt(x, y, z)) {
+					h.SetHeight(x, z, y+1)
+					break
+				}
+			}
+		}
+	}
+	return h
+}
+
+func TestComputeHeightmap(t *testing.T) {
+	notStone := func(state BlockState) bool {
+		return NotAir(state) && !state.Equal(stone)
+	}
+
+	tests := []struct {
+		name  string
+		chunk map[string]interface{}
+		edit  func(c *Chunk)
+		match func(BlockState) bool
+	}{
+		{name: "flat", chunk: flatChunk(), match: NotAir},
+		{
+			name:  "flat edited",
+			chunk: flatChunk(),
+			edit: func(c *Chunk) {
+				c.SetBlock(5, 40, 6, dirt)
+				c.SetBlock(0, 3, 0, Air)
+				c.SetBlock(15, -64, 15, dirt)
+			},
+			match: NotAir,
+		},
+		{
+			name:  "nothing matches",
+			chunk: flatChunk(),
+			match: func(BlockState) bool { return false },
+		},
+		{
+			name:  "buried blocks",
+			chunk: flatChunk(),
+			edit: func(c *Chunk) {
+				c.SetBlock(15, -64, 15, dirt)
+				c.SetBlock(8, 2, 8, dirt)
+			},
+			match: notStone,
+		},
+		{name: "level", chunk: levelChunk(), match: NotAir},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Decode(nbt.Marshal("", tt.chunk))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.edit != nil {
+				tt.edit(c)
+			}
+
+			got, want := c.ComputeHeightmap(tt.match), bruteHeightmap(c, tt.match)
+			if *got != *want {
+				t.Errorf("ComputeHeightmap() = %v, want %v", got.heights, want.heights)
+			}
+		})
+	}
+
+	c, _ := Decode(nbt.Marshal("", flatChunk()))
+	c.SetBlock(5, 40, 6, dirt)
+	h := c.ComputeHeightmap(NotAir)
+	if got := h.Height(5, 6); got != 41 {
+		t.Errorf("Height(5, 6) = %d, want 41", got)
+	}
+	if got := h.Height(0, 0); got != 4 {
+		t.Errorf("Height(0, 0) = %d, want 4", got)
+	}
+}
+
+func TestHeightmapRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk map[string]interface{}
+		longs int
+	}{
+		{name: "flat", chunk: flatChunk(), longs: 37},
+		{name: "level", chunk: levelChunk(), longs: 36},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Decode(nbt.Marshal("", tt.chunk))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h, err := c.Heightmap(MotionBlocking); h != nil || err != nil {
+				t.Fatalf("Heightmap() of a chunk without heightmaps = %v, %v", h, err)
+			}
+
+			want := c.ComputeHeightmap(NotAir)
+			c.SetHeightmap(WorldSurface, want)
+
+			c, err = Decode(c.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			heightmaps := decodeTree(t, c.Encode())
+			if c.Legacy() {
+				heightmaps = heightmaps["Level"].(map[string]interface{})
+			}
+			if data := heightmaps["Heightmaps"].(map[string]interface{})[WorldSurface].([]int64); len(data) != tt.longs {
+				t.Errorf("heightmap stored in %d longs, want %d", len(data), tt.longs)
+			}
+
+			got, err := c.Heightmap(WorldSurface)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *want {
+				t.Errorf("Heightmap() = %v, want %v", got.heights, want.heights)
+			}
+		})
+	}
+}
+
+func TestMinY(t *testing.T) {
+	flat := flatChunk()
+	c, _ := Decode(nbt.Marshal("", flat))
+	if got := c.MinY(); got != -64 {
+		t.Errorf("MinY() from sections = %d, want -64", got)
+	}
+
+	flat["yPos"] = int32(-5)
+	c, _ = Decode(nbt.Marshal("", flat))
+	if got := c.MinY(); got != -80 {
+		t.Errorf("MinY() from yPos = %d, want -80", got)
+	}
+
+	c, _ = Decode(nbt.Marshal("", levelChunk()))
+	if got := c.MinY(); got != 0 {
+		t.Errorf("MinY() before 1.18 = %d, want 0", got)
+	}
+}
@@ -0,0 +1,119 @@
+package chunk
+
+import "fmt"
+
+// NibbleLen is the length in bytes of the nibble arrays of a section, two 4-bit
+// values per byte for its 4096 blocks.
+const NibbleLen = 2048
+
+// NibbleArray holds a 4-bit value per block of a section, such as its sky light, its
+// block light or the data values of blocks before 1.13. The value of block i is in
+// the low nibble of byte i/2 when i is even and in the high nibble when it is odd.
+//
+// A NibbleArray is a view: converting the []byte of a TAG_Byte_Array to it shares the
+// bytes rather than copying them.
+type NibbleArray []byte
+
+// NewNibbleArray returns an array of zeros for a section.
+func NewNibbleArray() NibbleArray {
+	return make(NibbleArray, NibbleLen)
+}
+
+// PackNibbles returns an array holding values, which must fit in 4 bits.
+func PackNibbles(values []byte) NibbleArray {
+	a := make(NibbleArray, (len(values)+1)/2)
+	for i, v := range values {
+		a.SetIndex(i, v)
+	}
+	return a
+}
+
+// Len returns the number of values.
+func (a NibbleArray) Len() int {
+	return len(a) * 2
+}
+
+// Index returns value i.
+func (a NibbleArray) Index(i int) byte {
+	if i&1 == 0 {
+		return a[i>>1] & 0x0f
+	}
+	return a[i>>1] >> 4
+}
+
+// SetIndex sets value i to v, which must fit in 4 bits.
+func (a NibbleArray) SetIndex(i int, v byte) {
+	if v > 0x0f {
+		panic(fmt.Sprintf("chunk: value %d does not fit in a nibble", v))
+	}
+	if i&1 == 0 {
+		a[i>>1] = a[i>>1]&0xf0 | v
+	} else {
+		a[i>>1] = a[i>>1]&0x0f | v<<4
+	}
+}
+
+// Get returns the value of the block at x, y, z within the section.
+func (a NibbleArray) Get(x, y, z int) byte {
+	return a.Index(blockIndex(x, y, z))
+}
+
+// Set sets the value of the block at x, y, z within the section.
+func (a NibbleArray) Set(x, y, z int, v byte) {
+	a.SetIndex(blockIndex(x, y, z), v)
+}
+
+// Fill sets every value to v.
+func (a NibbleArray) Fill(v byte) {
+	if v > 0x0f {
+		panic(fmt.Sprintf("chunk: value %d does not fit in a nibble", v))
+	}
+	for i := range a {
+		a[i] = v<<4 | v
+	}
+}
+
+// Values returns every value, one per byte.
+func (a NibbleArray) Values() []byte {
+	values := make([]byte, a.Len())
+	for i := range values {
+		values[i] = a.Index(i)
+	}
+	return values
+}
+
+// SkyLight returns the sky light of the section, or nil if it has none.
+func (s *Section) SkyLight() NibbleArray {
+	return s.nibbles("SkyLight")
+}
+
+// BlockLight returns the block light of the section, or nil if it has none.
+func (s *Section) BlockLight() NibbleArray {
+	return s.nibbles("BlockLight")
+}
+
+// SetSkyLight stores the sky light of the section. A nil array removes it.
+func (s *Section) SetSkyLight(a NibbleArray) {
+	s.setNibbles("SkyLight", a)
+}
+
+// SetBlockLight stores the block light of the section. A nil array removes it.
+func (s *Section) SetBlockLight(a NibbleArray) {
+	s.setNibbles("BlockLight", a)
+}
+
+func (s *Section) nibbles(key string) NibbleArray {
+	b, _ := s.raw[key].([]byte)
+	if len(b) != NibbleLen {
+		return nil
+	}
+	return NibbleArray(b)
+}
+
+func (s *Section) setNibbles(key string, a NibbleArray) {
+	if a == nil {
+		delete(s.raw, key)
+		return
+	}
+	s.raw[key] = []byte(a)
+}
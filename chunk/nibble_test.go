@@ -0,0 +1,85 @@
+package chunk
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt"
+	"reflect"
+	"testing"
+)
+
+func TestNibbleArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, y, z int
+		v       byte
+		index   int
+		want    byte
+	}{
+		{name: "first block in low nibble", x: 0, y: 0, z: 0, v: 0xa, index: 0, want: 0x0a},
+		{name: "second block in high nibble", x: 1, y: 0, z: 0, v: 0xb, index: 0, want: 0xb0},
+		{name: "z before y", x: 0, y: 0, z: 1, v: 0x3, index: 8, want: 0x03},
+		{name: "last block", x: 15, y: 15, z: 15, v: 0xf, index: 2047, want: 0xf0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewNibbleArray()
+			a.Set(tt.x, tt.y, tt.z, tt.v)
+			if a[tt.index] != tt.want {
+				t.Errorf("byte %d = %#x, want %#x", tt.index, a[tt.index], tt.want)
+			}
+			if got := a.Get(tt.x, tt.y, tt.z); got != tt.v {
+				t.Errorf("Get = %d, want %d", got, tt.v)
+			}
+		})
+	}
+}
+
+func TestNibbleArrayValues(t *testing.T) {
+	values := make([]byte, 4096)
+	for i := range values {
+		values[i] = byte(i*7) & 0x0f
+	}
+
+	a := PackNibbles(values)
+	if len(a) != NibbleLen {
+		t.Fatalf("packed %d bytes, want %d", len(a), NibbleLen)
+	}
+	if !reflect.DeepEqual(a.Values(), values) {
+		t.Errorf("values differ after packing")
+	}
+
+	a.Fill(0xc)
+	if !bytes.Equal(a, bytes.Repeat([]byte{0xcc}, NibbleLen)) {
+		t.Errorf("Fill(0xc) = %x...", a[:4])
+	}
+}
+
+func TestSectionLight(t *testing.T) {
+	c, err := Decode(nbt.Marshal("", levelChunk()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	below, blocks := c.Section(-1), c.Section(1)
+	if below.SkyLight() == nil || below.BlockLight() != nil {
+		t.Fatalf("light of the section below the blocks not found")
+	}
+	below.SkyLight().Set(1, 2, 3, 15)
+	blocks.SetSkyLight(PackNibbles(bytes.Repeat([]byte{7}, 4096)))
+	blocks.SetBlockLight(nil)
+
+	c, err = Decode(c.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Section(-1).SkyLight().Get(1, 2, 3); got != 15 {
+		t.Errorf("sky light = %d, want 15", got)
+	}
+	if got := c.Section(1).SkyLight().Get(9, 9, 9); got != 7 {
+		t.Errorf("sky light = %d, want 7", got)
+	}
+	if c.Section(1).BlockLight() != nil {
+		t.Errorf("block light not removed")
+	}
+}
@@ -0,0 +1,174 @@
+// Package chunk decodes the block, biome and light data of chunks stored in region
+// files.
+package chunk
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// PackedArray is a fixed number of unsigned integers of a few bits each, packed into
+// the longs of a TAG_Long_Array. Chunk sections store their block states and biomes
+// this way, as indices into a palette.
+//
+// Since 1.16, entries never span two longs and the high bits of each long are left
+// unused when the entry size does not divide 64. Before, entries were packed
+// back to back and could span two longs.
+type PackedArray struct {
+	data     []int64
+	length   int
+	bits     int
+	spanning bool
+}
+
+// NewPackedArray returns an array of length entries of the given size, all zero. It
+// panics if the size is not between 0 and 32 bits.
+func NewPackedArray(length int, bits int, spanning bool) *PackedArray {
+	if err := checkBits(bits); err != nil {
+		panic(err)
+	}
+	return &PackedArray{
+		data:     make([]int64, packedLongs(length, bits, spanning)),
+		length:   length,
+		bits:     bits,
+		spanning: spanning,
+	}
+}
+
+// NewPackedArrayFrom returns an array of length entries of the given size, decoded from
+// data as read from a TAG_Long_Array. The array uses data without copying it.
+func NewPackedArrayFrom(data []int64, length int, bits int, spanning bool) (*PackedArray, error) {
+	if err := checkBits(bits); err != nil {
+		return nil, err
+	}
+	if want := packedLongs(length, bits, spanning); len(data) != want {
+		return nil, fmt.Errorf("chunk: %d entries of %d bits take %d longs, got %d", length, bits, want, len(data))
+	}
+	return &PackedArray{data: data, length: length, bits: bits, spanning: spanning}, nil
+}
+
+// checkBits returns an error unless the entry size is between 0 and 32 bits. Larger
+// sizes would overflow the mask of Set and, past 64, divide by zero in packedLongs.
+func checkBits(bits int) error {
+	if bits < 0 || bits > 32 {
+		return fmt.Errorf("chunk: invalid entry size %d", bits)
+	}
+	return nil
+}
+
+// packedLongs returns the number of longs holding length entries of the given size.
+func packedLongs(length int, bits int, spanning bool) int {
+	if bits == 0 {
+		return 0
+	}
+	if spanning {
+		return (length*bits + 63) / 64
+	}
+	perLong := 64 / bits
+	return (length + perLong - 1) / perLong
+}
+
+// BitsFor returns the entry size needed to index a palette of n entries, ceil(log2(n)),
+// but at least min. Vanilla uses a minimum of 4 bits for block states.
+func BitsFor(n int, min int) int {
+	b := 0
+	if n > 1 {
+		b = bits.Len(uint(n - 1))
+	}
+	if b < min {
+		return min
+	}
+	return b
+}
+
+// Len returns the number of entries.
+func (a *PackedArray) Len() int {
+	return a.length
+}
+
+// Bits returns the size of an entry.
+func (a *PackedArray) Bits() int {
+	return a.bits
+}
+
+// Spanning reports whether entries may span two longs, as before 1.16.
+func (a *PackedArray) Spanning() bool {
+	return a.spanning
+}
+
+// Longs returns the packed entries, to be stored as a TAG_Long_Array.
+func (a *PackedArray) Longs() []int64 {
+	return a.data
+}
+
+// position returns the long holding entry i and the offset of the entry in it.
+func (a *PackedArray) position(i int) (int, int) {
+	if a.spanning {
+		bit := i * a.bits
+		return bit / 64, bit % 64
+	}
+	perLong := 64 / a.bits
+	return i / perLong, i % perLong * a.bits
+}
+
+// Get returns entry i.
+func (a *PackedArray) Get(i int) int {
+	if i < 0 || i >= a.length {
+		panic(fmt.Sprintf("chunk: index %d out of range [0, %d)", i, a.length))
+	}
+	if a.bits == 0 {
+		return 0
+	}
+
+	mask := uint64(1)<<a.bits - 1
+	l, offset := a.position(i)
+	v := uint64(a.data[l]) >> offset
+	if offset+a.bits > 64 {
+		v |= uint64(a.data[l+1]) << (64 - offset)
+	}
+	return int(v & mask)
+}
+
+// Set sets entry i to v, which must fit in the entry size.
+func (a *PackedArray) Set(i int, v int) {
+	if i < 0 || i >= a.length {
+		panic(fmt.Sprintf("chunk: index %d out of range [0, %d)", i, a.length))
+	}
+	mask := uint64(1)<<a.bits - 1
+	if v < 0 || uint64(v) > mask {
+		panic(fmt.Sprintf("chunk: value %d does not fit in %d bits", v, a.bits))
+	}
+	if a.bits == 0 {
+		return
+	}
+
+	l, offset := a.position(i)
+	a.data[l] = int64(uint64(a.data[l])&^(mask<<offset) | uint64(v)<<offset)
+	if offset+a.bits > 64 {
+		spill := 64 - offset
+		a.data[l+1] = int64(uint64(a.data[l+1])&^(mask>>spill) | uint64(v)>>spill)
+	}
+}
+
+// Values returns every entry.
+func (a *PackedArray) Values() []int {
+	values := make([]int, a.length)
+	for i := range values {
+		values[i] = a.Get(i)
+	}
+	return values
+}
+
+// Resize repacks the entries with a new entry size, such as when the palette grows
+// past the entries the current size can index. Entries must fit in the new size, which
+// like for NewPackedArray is between 0 and 32 bits.
+func (a *PackedArray) Resize(bits int) {
+	if bits == a.bits {
+		return
+	}
+	resized := NewPackedArray(a.length, bits, a.spanning)
+	for i := 0; i < a.length; i++ {
+		resized.Set(i, a.Get(i))
+	}
+	*a = *resized
+}
@@ -0,0 +1,170 @@
+package chunk
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestPackedArrayLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		bits     int
+		spanning bool
+		values   []int
+		want     []int64
+	}{
+		{
+			name:   "4 bits",
+			bits:   4,
+			values: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 1},
+			want:   []int64{-0x0123456789abcdf0, 1},
+		},
+		{
+			name:   "5 bits leave 4 bits unused",
+			bits:   5,
+			values: []int{31, 31, 31, 31, 31, 31, 31, 31, 31, 31, 31, 31, 31},
+			want:   []int64{0x0fffffffffffffff, 31},
+		},
+		{
+			name:     "5 bits spanning",
+			bits:     5,
+			spanning: true,
+			values:   []int{31, 31, 31, 31, 31, 31, 31, 31, 31, 31, 31, 31, 31},
+			want:     []int64{-1, 1},
+		},
+		{
+			name:     "entry split across longs",
+			bits:     5,
+			spanning: true,
+			values:   []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x15},
+			want:     []int64{0x5 << 60, 0x1},
+		},
+		{
+			name:   "no bits",
+			bits:   0,
+			values: []int{0, 0, 0},
+			want:   []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewPackedArray(len(tt.values), tt.bits, tt.spanning)
+			for i, v := range tt.values {
+				a.Set(i, v)
+			}
+			if !reflect.DeepEqual(a.Longs(), tt.want) {
+				t.Errorf("Longs() = %#x, want %#x", a.Longs(), tt.want)
+			}
+
+			decoded, err := NewPackedArrayFrom(tt.want, len(tt.values), tt.bits, tt.spanning)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded.Values(), tt.values) {
+				t.Errorf("Values() = %v, want %v", decoded.Values(), tt.values)
+			}
+		})
+	}
+}
+
+func TestPackedArrayRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, spanning := range []bool{false, true} {
+		for bits := 1; bits <= 32; bits++ {
+			values := make([]int, 4096)
+			a := NewPackedArray(len(values), bits, spanning)
+			for i := range values {
+				values[i] = int(rng.Uint32() & (1<<bits - 1))
+				a.Set(i, values[i])
+			}
+			if got := a.Values(); !reflect.DeepEqual(got, values) {
+				t.Errorf("%d bits, spanning %v: values differ", bits, spanning)
+			}
+		}
+	}
+}
+
+func TestPackedArraySize(t *testing.T) {
+	tests := []struct {
+		bits     int
+		spanning bool
+		want     int
+	}{
+		{bits: 4, want: 256},
+		{bits: 5, want: 342},
+		{bits: 5, spanning: true, want: 320},
+		{bits: 6, want: 410},
+		{bits: 14, want: 1024},
+		{bits: 14, spanning: true, want: 896},
+	}
+
+	for _, tt := range tests {
+		if got := len(NewPackedArray(4096, tt.bits, tt.spanning).Longs()); got != tt.want {
+			t.Errorf("%d bits, spanning %v: %d longs, want %d", tt.bits, tt.spanning, got, tt.want)
+		}
+	}
+
+	if _, err := NewPackedArrayFrom(make([]int64, 255), 4096, 4, false); err == nil {
+		t.Errorf("expected an error decoding too few longs")
+	}
+
+	for _, bits := range []int{-1, 33, 64, 65} {
+		if _, err := NewPackedArrayFrom(nil, 4096, bits, false); err == nil {
+			t.Errorf("expected an error decoding entries of %d bits", bits)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic creating entries of %d bits", bits)
+				}
+			}()
+			NewPackedArray(4096, bits, false)
+		}()
+	}
+}
+
+func TestPackedArrayResize(t *testing.T) {
+	for _, spanning := range []bool{false, true} {
+		a := NewPackedArray(4096, 4, spanning)
+		for i := 0; i < a.Len(); i++ {
+			a.Set(i, i%16)
+		}
+		a.Resize(5)
+		a.Set(100, 31)
+
+		if a.Bits() != 5 || len(a.Longs()) != packedLongs(4096, 5, spanning) {
+			t.Errorf("resized to %d bits in %d longs", a.Bits(), len(a.Longs()))
+		}
+		for i := 0; i < a.Len(); i++ {
+			want := i % 16
+			if i == 100 {
+				want = 31
+			}
+			if got := a.Get(i); got != want {
+				t.Fatalf("entry %d = %d after resizing, want %d", i, got, want)
+			}
+		}
+	}
+}
+
+func TestBitsFor(t *testing.T) {
+	tests := []struct {
+		n, min, want int
+	}{
+		{n: 1, min: 0, want: 0},
+		{n: 2, min: 0, want: 1},
+		{n: 16, min: 4, want: 4},
+		{n: 17, min: 4, want: 5},
+		{n: 3, min: 4, want: 4},
+		{n: 64, min: 1, want: 6},
+		{n: 65, min: 1, want: 7},
+	}
+
+	for _, tt := range tests {
+		if got := BitsFor(tt.n, tt.min); got != tt.want {
+			t.Errorf("BitsFor(%d, %d) = %d, want %d", tt.n, tt.min, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package chunk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BlockState is a block and the values of its properties, as stored in the palettes
+// of chunk sections.
+type BlockState struct {
+	Name       string
+	Properties map[string]string
+}
+
+// Air is the block of sections and palettes that hold nothing else.
+var Air = BlockState{Name: "minecraft:air"}
+
+// Equal reports whether b and o are the same block with the same properties.
+func (b BlockState) Equal(o BlockState) bool {
+	if b.Name != o.Name || len(b.Properties) != len(o.Properties) {
+		return false
+	}
+	for key, value := range b.Properties {
+		if v, ok := o.Properties[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the state the way commands accept it, such as
+// minecraft:oak_log[axis=y].
+func (b BlockState) String() string {
+	if len(b.Properties) == 0 {
+		return b.Name
+	}
+
+	keys := make([]string, 0, len(b.Properties))
+	for key := range b.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sb := strings.Builder{}
+	sb.WriteString(b.Name)
+	for i, key := range keys {
+		if i == 0 {
+			sb.WriteByte('[')
+		} else {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(key + "=" + b.Properties[key])
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+func blockStateFromTree(tree interface{}) BlockState {
+	m, _ := tree.(map[string]interface{})
+	name, _ := m["Name"].(string)
+	state := BlockState{Name: name}
+	if properties, ok := m["Properties"].(map[string]interface{}); ok && len(properties) > 0 {
+		state.Properties = make(map[string]string, len(properties))
+		for key, value := range properties {
+			state.Properties[key], _ = value.(string)
+		}
+	}
+	return state
+}
+
+func (b BlockState) tree() map[string]interface{} {
+	m := map[string]interface{}{"Name": b.Name}
+	if len(b.Properties) > 0 {
+		properties := make(map[string]interface{}, len(b.Properties))
+		for key, value := range b.Properties {
+			properties[key] = value
+		}
+		m["Properties"] = properties
+	}
+	return m
+}
+
+// container holds the palette indices of the blocks or biomes of a section.
+type container struct {
+	size     int
+	minBits  int
+	spanning bool
+
+	// omitSingle drops the data when the palette has a single entry, as done since
+	// 1.18, rather than storing indices that are all 0.
+	omitSingle bool
+
+	// data is nil when every index is 0.
+	data *PackedArray
+}
+
+func (c *container) get(i int) int {
+	if c.data == nil {
+		return 0
+	}
+	return c.data.Get(i)
+}
+
+// check returns an error if an index is out of a palette of paletteLen entries.
+func (c *container) check(paletteLen int) error {
+	for i := 0; i < c.size; i++ {
+		if v := c.get(i); v >= paletteLen {
+			return fmt.Errorf("index %d of entry %d is out of a palette of %d", v, i, paletteLen)
+		}
+	}
+	return nil
+}
+
+// set stores index v at i, growing the entries to fit a palette of paletteLen entries.
+func (c *container) set(i int, v int, paletteLen int) {
+	bits := BitsFor(paletteLen, c.minBits)
+	if c.data == nil {
+		if v == 0 {
+			return
+		}
+		c.data = NewPackedArray(c.size, bits, c.spanning)
+	} else if bits > c.data.Bits() {
+		c.data.Resize(bits)
+	}
+	c.data.Set(i, v)
+}
+
+// compact drops the palette entries no index refers to and shrinks the entries to fit
+// the remaining ones. It returns the previous indices of the entries kept, in order.
+func (c *container) compact(paletteLen int) []int {
+	used := make([]bool, paletteLen)
+	for i := 0; i < c.size; i++ {
+		used[c.get(i)] = true
+	}
+
+	kept := make([]int, 0, paletteLen)
+	remap := make([]int, paletteLen)
+	for old, u := range used {
+		if u {
+			remap[old] = len(kept)
+			kept = append(kept, old)
+		}
+	}
+
+	if len(kept) == 1 && c.omitSingle {
+		c.data = nil
+		return kept
+	}
+
+	data := NewPackedArray(c.size, BitsFor(len(kept), c.minBits), c.spanning)
+	for i := 0; i < c.size; i++ {
+		data.Set(i, remap[c.get(i)])
+	}
+	c.data = data
+	return kept
+}
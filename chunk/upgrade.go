@@ -0,0 +1,320 @@
+package chunk
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/junglemc/nbt/region"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// VersionFlattening is the data version of 1.13, the first to store blocks as
+// palettes of states rather than numeric IDs.
+const VersionFlattening = 1519
+
+// LegacyBlock is a block before 1.13: a numeric ID and a 4-bit data value.
+type LegacyBlock struct {
+	ID   int
+	Data byte
+}
+
+func (b LegacyBlock) String() string {
+	return fmt.Sprintf("%d:%d", b.ID, b.Data)
+}
+
+// MappingTable maps blocks before 1.13 to their states.
+type MappingTable map[LegacyBlock]BlockState
+
+// Lookup returns the state of b, falling back on the state of its ID with data value
+// 0 like the game does for data values it does not know.
+func (t MappingTable) Lookup(b LegacyBlock) (BlockState, bool) {
+	if state, ok := t[b]; ok {
+		return state, true
+	}
+	state, ok := t[LegacyBlock{ID: b.ID}]
+	return state, ok
+}
+
+// ReadMappingTable reads a mapping table with one block per line: its ID, optionally
+// followed by a colon and its data value, and its state as formatted by
+// BlockState.String. Empty lines and lines starting with # are skipped.
+//
+//	1 minecraft:stone
+//	1:1 minecraft:granite
+//	17:4 minecraft:oak_log[axis=x]
+func ReadMappingTable(r io.Reader) (MappingTable, error) {
+	t := make(MappingTable)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("mapping line %d: expected a block and a state", line)
+		}
+		b, err := parseLegacyBlock(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("mapping line %d: %w", line, err)
+		}
+		state, err := ParseBlockState(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("mapping line %d: %w", line, err)
+		}
+		t[b] = state
+	}
+	return t, scanner.Err()
+}
+
+func parseLegacyBlock(s string) (LegacyBlock, error) {
+	idText, dataText := s, "0"
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		idText, dataText = s[:i], s[i+1:]
+	}
+	id, err := strconv.Atoi(idText)
+	if err != nil || id < 0 || id > 0xfff {
+		return LegacyBlock{}, fmt.Errorf("invalid block ID %q", idText)
+	}
+	data, err := strconv.Atoi(dataText)
+	if err != nil || data < 0 || data > 0xf {
+		return LegacyBlock{}, fmt.Errorf("invalid data value %q", dataText)
+	}
+	return LegacyBlock{ID: id, Data: byte(data)}, nil
+}
+
+// ParseBlockState parses a state formatted by BlockState.String, such as
+// minecraft:oak_log[axis=y].
+func ParseBlockState(s string) (BlockState, error) {
+	i := strings.IndexByte(s, '[')
+	if i < 0 {
+		if s == "" {
+			return BlockState{}, fmt.Errorf("empty block state")
+		}
+		return BlockState{Name: s}, nil
+	}
+	if i == 0 || !strings.HasSuffix(s, "]") {
+		return BlockState{}, fmt.Errorf("invalid block state %q", s)
+	}
+
+	state := BlockState{Name: s[:i], Properties: make(map[string]string)}
+	for _, property := range strings.Split(s[i+1:len(s)-1], ",") {
+		kv := strings.SplitN(property, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return BlockState{}, fmt.Errorf("invalid property %q of block state %q", property, s)
+		}
+		state.Properties[kv[0]] = kv[1]
+	}
+	return state, nil
+}
+
+// UpgradeOptions configures the conversion of chunks from before 1.13.
+type UpgradeOptions struct {
+	// Mapping returns the state of a block, or false if it is unknown, such as the
+	// Lookup method of a MappingTable. It is required, and not called for air, ID 0.
+	Mapping func(b LegacyBlock) (BlockState, bool)
+
+	// Unknown replaces the blocks the mapping does not know. It defaults to air.
+	Unknown BlockState
+
+	// DataVersion is written to converted chunks and decides the layout of their
+	// block states. Converted chunks keep the Level compound, so it must be at least
+	// VersionFlattening and below VersionFlat, the versions using that layout. It
+	// defaults to VersionFlattening.
+	DataVersion int32
+}
+
+// UpgradeResult counts what an upgrade converted.
+type UpgradeResult struct {
+	Chunks   int
+	Sections int
+
+	// Skipped counts the chunks that had no numeric block IDs to convert.
+	Skipped int
+
+	// Unknown counts the blocks the mapping did not know, by ID and data value.
+	Unknown map[LegacyBlock]int
+}
+
+func (r *UpgradeResult) add(o UpgradeResult) {
+	r.Chunks += o.Chunks
+	r.Sections += o.Sections
+	r.Skipped += o.Skipped
+	for b, n := range o.Unknown {
+		if r.Unknown == nil {
+			r.Unknown = make(map[LegacyBlock]int)
+		}
+		r.Unknown[b] += n
+	}
+}
+
+// Upgrade converts the uncompressed NBT data of a chunk from before 1.13, whose
+// sections store numeric block IDs in Blocks, Add and Data, to sections holding a
+// palette of states and packed indices.
+//
+// The converted chunk keeps its Level compound, like chunks up to 1.17, and is
+// stamped with opts.DataVersion, so the game no longer converts any of it from 1.12
+// when loading it. Besides the blocks, Upgrade converts Biomes to a TAG_Int_Array,
+// derives Status from TerrainPopulated, and adds the WorldSurface heightmap; other
+// keys are left as they are and must already be in the format of the new version.
+// Tile entities and entities are not converted, as the items and blocks they hold
+// changed with 1.13, so chunks holding any are refused. Chunks without numeric block
+// IDs are returned unchanged.
+func Upgrade(data []byte, opts UpgradeOptions) ([]byte, UpgradeResult, error) {
+	var result UpgradeResult
+	if opts.Mapping == nil {
+		return nil, result, fmt.Errorf("chunk: no block mapping")
+	}
+	if opts.DataVersion == 0 {
+		opts.DataVersion = VersionFlattening
+	}
+	if opts.DataVersion < VersionFlattening || opts.DataVersion >= VersionFlat {
+		return nil, result, fmt.Errorf("chunk: cannot upgrade to data version %d, want %d to %d", opts.DataVersion, VersionFlattening, VersionFlat-1)
+	}
+	c, err := Decode(data)
+	if err != nil {
+		return nil, result, err
+	}
+	if !c.Legacy() || !c.preFlattening() {
+		result.Skipped++
+		return data, result, nil
+	}
+	for _, key := range []string{"TileEntities", "Entities"} {
+		if list, _ := c.level[key].([]interface{}); len(list) > 0 {
+			return nil, result, fmt.Errorf("chunk %d,%d: cannot upgrade %s", c.X, c.Z, key)
+		}
+	}
+
+	if opts.Unknown.Name == "" {
+		opts.Unknown = Air
+	}
+	c.DataVersion = opts.DataVersion
+
+	for _, s := range c.sections {
+		if _, ok := s.raw["Blocks"]; !ok {
+			continue
+		}
+		if err = c.upgradeSection(s, opts, &result); err != nil {
+			return nil, result, fmt.Errorf("chunk %d,%d: section %d: %w", c.X, c.Z, s.Y, err)
+		}
+		result.Sections++
+	}
+
+	if biomes, ok := c.level["Biomes"].([]byte); ok {
+		ids := make([]int32, len(biomes))
+		for i, b := range biomes {
+			ids[i] = int32(b)
+		}
+		c.level["Biomes"] = ids
+	}
+	if _, ok := c.level["Status"]; !ok {
+		if populated, _ := c.level["TerrainPopulated"].(byte); populated != 0 {
+			c.level["Status"] = "postprocessed"
+		} else {
+			c.level["Status"] = "liquid_carved"
+		}
+	}
+	c.SetHeightmap(WorldSurface, c.ComputeHeightmap(NotAir))
+
+	result.Chunks++
+	return c.Encode(), result, nil
+}
+
+// preFlattening reports whether a section of the chunk holds numeric block IDs.
+func (c *Chunk) preFlattening() bool {
+	for _, s := range c.sections {
+		if _, ok := s.raw["Blocks"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Chunk) upgradeSection(s *Section, opts UpgradeOptions, result *UpgradeResult) error {
+	blocks, _ := s.raw["Blocks"].([]byte)
+	if len(blocks) != 4096 {
+		return fmt.Errorf("Blocks holds %d bytes, want 4096", len(blocks))
+	}
+	data, _ := s.raw["Data"].([]byte)
+	if data == nil {
+		data = NewNibbleArray()
+	}
+	add, _ := s.raw["Add"].([]byte)
+	if len(data) != NibbleLen || add != nil && len(add) != NibbleLen {
+		return fmt.Errorf("Data or Add does not hold %d bytes", NibbleLen)
+	}
+
+	s.blocks = c.newSection(s.Y).blocks
+	s.palette = []BlockState{Air}
+
+	type mapped struct {
+		entry int
+		known bool
+	}
+	cache := make(map[LegacyBlock]mapped)
+	for i, id := range blocks {
+		b := LegacyBlock{ID: int(id), Data: NibbleArray(data).Index(i)}
+		if add != nil {
+			b.ID |= int(NibbleArray(add).Index(i)) << 8
+		}
+
+		m, ok := cache[b]
+		if !ok {
+			state, known := Air, true
+			if b.ID != 0 {
+				state, known = opts.Mapping(b)
+			}
+			if m.known = known; !known {
+				state = opts.Unknown
+			}
+			m.entry = s.paletteEntry(state)
+			cache[b] = m
+		}
+		if !m.known {
+			if result.Unknown == nil {
+				result.Unknown = make(map[LegacyBlock]int)
+			}
+			result.Unknown[b]++
+		}
+		s.blocks.set(i, m.entry, len(s.palette))
+	}
+
+	delete(s.raw, "Blocks")
+	delete(s.raw, "Add")
+	delete(s.raw, "Data")
+	return nil
+}
+
+// UpgradeRegion upgrades every chunk of a region file with Upgrade, writing the
+// converted chunks back in place. The space of the previous versions of the chunks is
+// left free; region.Compact reclaims it.
+func UpgradeRegion(name string, opts UpgradeOptions) (UpgradeResult, error) {
+	var result UpgradeResult
+	f, err := region.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	for _, info := range f.Chunks() {
+		data, err := f.ReadChunk(info.X, info.Z)
+		if err != nil {
+			return result, err
+		}
+		upgraded, r, err := Upgrade(data, opts)
+		result.add(r)
+		if err != nil {
+			return result, err
+		}
+		if r.Chunks == 0 {
+			continue
+		}
+		if err = f.WriteChunk(info.X, info.Z, upgraded, region.CompressionZlib); err != nil {
+			return result, err
+		}
+	}
+	return result, f.Close()
+}
@@ -0,0 +1,379 @@
+package chunk
+
+import (
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/region"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMapping = `
+# stone and its variants
+1 minecraft:stone
+1:1 minecraft:granite
+2 minecraft:grass_block[snowy=false]
+17:4 minecraft:oak_log[axis=x]
+17 minecraft:oak_log[axis=y]
+300 mod:machine
+`
+
+func mappingTable(t *testing.T) MappingTable {
+	t.Helper()
+
+	table, err := ReadMappingTable(strings.NewReader(testMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+// preFlatteningChunk returns a 1.12 chunk whose section at y 0 to 15 holds granite
+// below y 1, grass at y 1, an oak log at 0, 2, 0 and air above, and whose section at
+// y 16 to 31 holds a block whose ID needs the Add array and one of ID 99, unknown to
+// the test mapping.
+func preFlatteningChunk() map[string]interface{} {
+	blocks, data := make([]byte, 4096), NewNibbleArray()
+	for i := 0; i < 256; i++ {
+		blocks[i] = 1
+		data.SetIndex(i, 1)
+		blocks[256+i] = 2
+	}
+	blocks[blockIndex(0, 2, 0)] = 17
+	data.Set(0, 2, 0, 4)
+	blocks[blockIndex(1, 2, 0)] = 17
+	data.Set(1, 2, 0, 9)
+
+	upper, add := make([]byte, 4096), NewNibbleArray()
+	upper[blockIndex(3, 0, 3)] = 300 & 0xff
+	add.Set(3, 0, 3, 300>>8)
+	upper[blockIndex(4, 0, 4)] = 99
+
+	return map[string]interface{}{
+		"DataVersion": int32(1343),
+		"Level": map[string]interface{}{
+			"xPos":             int32(2),
+			"zPos":             int32(5),
+			"TerrainPopulated": byte(1),
+			"Biomes":           make([]byte, 256),
+			"Entities":         []interface{}{},
+			"Sections": []interface{}{
+				map[string]interface{}{
+					"Y":          byte(0),
+					"Blocks":     blocks,
+					"Data":       []byte(data),
+					"SkyLight":   make([]byte, 2048),
+					"BlockLight": make([]byte, 2048),
+				},
+				map[string]interface{}{
+					"Y":          byte(1),
+					"Blocks":     upper,
+					"Add":        []byte(add),
+					"Data":       make([]byte, 2048),
+					"SkyLight":   make([]byte, 2048),
+					"BlockLight": make([]byte, 2048),
+				},
+			},
+		},
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        UpgradeOptions
+		version     int32
+		longs       int
+		unknownName string
+		unknownTop  int
+	}{
+		{name: "1.13", version: VersionFlattening, longs: 256, unknownName: "minecraft:air", unknownTop: 2},
+		{
+			name:        "1.16 with unknown block",
+			opts:        UpgradeOptions{DataVersion: 2586, Unknown: BlockState{Name: "minecraft:bedrock"}},
+			version:     2586,
+			longs:       256,
+			unknownName: "minecraft:bedrock",
+			unknownTop:  17,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Mapping = mappingTable(t).Lookup
+			data, result, err := Upgrade(nbt.Marshal("", preFlatteningChunk()), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Chunks != 1 || result.Sections != 2 || result.Skipped != 0 {
+				t.Errorf("result = %+v", result)
+			}
+			if want := map[LegacyBlock]int{{ID: 99}: 1}; !reflect.DeepEqual(result.Unknown, want) {
+				t.Errorf("unknown blocks = %v, want %v", result.Unknown, want)
+			}
+
+			c, err := Decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.DataVersion != tt.version || c.X != 2 || c.Z != 5 {
+				t.Errorf("chunk %d,%d of version %d", c.X, c.Z, c.DataVersion)
+			}
+
+			want := map[[3]int]string{
+				{0, 0, 0}:   "minecraft:granite",
+				{15, 0, 15}: "minecraft:granite",
+				{7, 1, 7}:   "minecraft:grass_block[snowy=false]",
+				{0, 2, 0}:   "minecraft:oak_log[axis=x]",
+				{1, 2, 0}:   "minecraft:oak_log[axis=y]",
+				{2, 2, 0}:   "minecraft:air",
+				{3, 16, 3}:  "mod:machine",
+				{4, 16, 4}:  tt.unknownName,
+			}
+			for pos, state := range want {
+				if got := c.BlockAt(pos[0], pos[1], pos[2]).String(); got != state {
+					t.Errorf("BlockAt(%v) = %s, want %s", pos, got, state)
+				}
+			}
+
+			tree := decodeTree(t, data)
+			level := tree["Level"].(map[string]interface{})
+			section := level["Sections"].([]interface{})[0].(map[string]interface{})
+			for _, key := range []string{"Blocks", "Add", "Data"} {
+				if _, ok := section[key]; ok {
+					t.Errorf("%s kept after upgrading", key)
+				}
+			}
+			if _, ok := section["SkyLight"]; !ok {
+				t.Errorf("SkyLight dropped")
+			}
+			if got := len(section["BlockStates"].([]int64)); got != tt.longs {
+				t.Errorf("BlockStates holds %d longs, want %d", got, tt.longs)
+			}
+			if _, ok := level["Biomes"].([]int32); !ok {
+				t.Errorf("Biomes is %T, want []int32", level["Biomes"])
+			}
+			if level["Status"] != "postprocessed" {
+				t.Errorf("Status = %v, want postprocessed", level["Status"])
+			}
+
+			h, err := c.Heightmap(WorldSurface)
+			if err != nil || h == nil {
+				t.Fatalf("Heightmap() = %v, %v", h, err)
+			}
+			if got := h.Height(0, 0); got != 3 {
+				t.Errorf("height of the log column = %d, want 3", got)
+			}
+			if got := h.Height(4, 4); got != tt.unknownTop {
+				t.Errorf("height of the unknown block column = %d, want %d", got, tt.unknownTop)
+			}
+		})
+	}
+}
+
+func TestUpgradeSpanning(t *testing.T) {
+	tree := preFlatteningChunk()
+	section := tree["Level"].(map[string]interface{})["Sections"].([]interface{})[0].(map[string]interface{})
+	blocks, data := section["Blocks"].([]byte), NibbleArray(section["Data"].([]byte))
+	table := MappingTable{}
+	for i := 0; i < 17; i++ {
+		blocks[i] = 35
+		data.SetIndex(i, byte(i%16))
+		table[LegacyBlock{ID: 35, Data: byte(i % 16)}] = BlockState{Name: "minecraft:wool", Properties: map[string]string{"color": string(rune('a' + i%16))}}
+	}
+	blocks[17] = 5
+	table[LegacyBlock{ID: 5}] = BlockState{Name: "minecraft:oak_planks"}
+	for b, state := range mappingTable(t) {
+		table[b] = state
+	}
+
+	tests := []struct {
+		version int32
+		longs   int
+	}{
+		{version: VersionFlattening, longs: 320},
+		{version: 2586, longs: 342},
+	}
+
+	for _, tt := range tests {
+		upgraded, _, err := Upgrade(nbt.Marshal("", tree), UpgradeOptions{Mapping: table.Lookup, DataVersion: tt.version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := Decode(upgraded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(c.Section(0).blocks.data.Longs()); got != tt.longs {
+			t.Errorf("version %d: BlockStates holds %d longs, want %d", tt.version, got, tt.longs)
+		}
+		if got := c.BlockAt(1, 0, 0).String(); got != "minecraft:wool[color=b]" {
+			t.Errorf("version %d: BlockAt(1, 0, 0) = %s", tt.version, got)
+		}
+		if got := c.BlockAt(1, 0, 1).String(); got != "minecraft:oak_planks" {
+			t.Errorf("version %d: BlockAt(1, 0, 1) = %s", tt.version, got)
+		}
+	}
+}
+
+func TestUpgradeSkipsFlattenedChunks(t *testing.T) {
+	for _, tree := range []map[string]interface{}{flatChunk(), levelChunk()} {
+		data := nbt.Marshal("", tree)
+		upgraded, result, err := Upgrade(data, UpgradeOptions{Mapping: mappingTable(t).Lookup})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Skipped != 1 || result.Chunks != 0 || !reflect.DeepEqual(upgraded, data) {
+			t.Errorf("flattened chunk converted: %+v", result)
+		}
+	}
+
+	if _, _, err := Upgrade(nbt.Marshal("", preFlatteningChunk()), UpgradeOptions{}); err == nil {
+		t.Errorf("expected an error upgrading without a mapping")
+	}
+}
+
+func TestUpgradeDataVersion(t *testing.T) {
+	tests := []struct {
+		version int32
+		wantErr bool
+	}{
+		{version: 0},
+		{version: VersionFlattening},
+		{version: VersionFlat - 1},
+		{version: VersionFlattening - 1, wantErr: true},
+		{version: VersionFlat, wantErr: true},
+		{version: 3000, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		_, _, err := Upgrade(nbt.Marshal("", preFlatteningChunk()), UpgradeOptions{Mapping: mappingTable(t).Lookup, DataVersion: tt.version})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("data version %d: error = %v, wantErr %v", tt.version, err, tt.wantErr)
+		}
+	}
+}
+
+func TestUpgradeRefusesEntities(t *testing.T) {
+	chest := map[string]interface{}{
+		"id": "minecraft:chest",
+		"x":  int32(32), "y": int32(1), "z": int32(80),
+		"Items": []interface{}{
+			map[string]interface{}{"Slot": byte(0), "id": "minecraft:wool", "Count": byte(64), "Damage": int16(14)},
+			map[string]interface{}{"Slot": byte(1), "id": "minecraft:bed", "Count": byte(1), "Damage": int16(11)},
+		},
+	}
+	pig := map[string]interface{}{"id": "minecraft:pig", "Pos": []interface{}{32.5, 2.0, 80.5}}
+
+	tests := []struct {
+		name   string
+		key    string
+		entity map[string]interface{}
+	}{
+		{name: "chest full of items", key: "TileEntities", entity: chest},
+		{name: "entity", key: "Entities", entity: pig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := preFlatteningChunk()
+			tree["Level"].(map[string]interface{})[tt.key] = []interface{}{tt.entity}
+			_, result, err := Upgrade(nbt.Marshal("", tree), UpgradeOptions{Mapping: mappingTable(t).Lookup})
+			if err == nil {
+				t.Fatalf("expected an error upgrading a chunk with %s", tt.key)
+			}
+			if result.Chunks != 0 {
+				t.Errorf("result = %+v", result)
+			}
+		})
+	}
+}
+
+func TestUpgradeRegion(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	f, err := region.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Marshal(2, 5, preFlatteningChunk()); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Marshal(3, 5, flatChunk()); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	result, err := UpgradeRegion(name, UpgradeOptions{Mapping: mappingTable(t).Lookup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Chunks != 1 || result.Skipped != 1 || result.Unknown[LegacyBlock{ID: 99}] != 1 {
+		t.Errorf("result = %+v", result)
+	}
+
+	f, err = region.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c, err := Read(f, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.BlockAt(0, 0, 0).String(); got != "minecraft:granite" {
+		t.Errorf("BlockAt(0, 0, 0) = %s, want minecraft:granite", got)
+	}
+}
+
+func TestReadMappingTable(t *testing.T) {
+	table := mappingTable(t)
+	tests := []struct {
+		block LegacyBlock
+		want  string
+		ok    bool
+	}{
+		{block: LegacyBlock{ID: 1}, want: "minecraft:stone", ok: true},
+		{block: LegacyBlock{ID: 1, Data: 1}, want: "minecraft:granite", ok: true},
+		{block: LegacyBlock{ID: 1, Data: 15}, want: "minecraft:stone", ok: true},
+		{block: LegacyBlock{ID: 17, Data: 4}, want: "minecraft:oak_log[axis=x]", ok: true},
+		{block: LegacyBlock{ID: 300}, want: "mod:machine", ok: true},
+		{block: LegacyBlock{ID: 99}},
+	}
+	for _, tt := range tests {
+		state, ok := table.Lookup(tt.block)
+		if ok != tt.ok || ok && state.String() != tt.want {
+			t.Errorf("Lookup(%v) = %s, %v, want %s, %v", tt.block, state, ok, tt.want, tt.ok)
+		}
+	}
+
+	for _, text := range []string{
+		"1",
+		"x minecraft:stone",
+		"1:16 minecraft:stone",
+		"4096 minecraft:stone",
+		"1 minecraft:stone[axis]",
+		"1 [axis=y]",
+	} {
+		if _, err := ReadMappingTable(strings.NewReader(text)); err == nil {
+			t.Errorf("expected an error reading %q", text)
+		}
+	}
+}
+
+func TestParseBlockState(t *testing.T) {
+	for _, s := range []string{
+		"minecraft:stone",
+		"minecraft:oak_log[axis=y]",
+		"minecraft:redstone_wire[east=side,north=none,power=15]",
+	} {
+		state, err := ParseBlockState(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.String() != s {
+			t.Errorf("ParseBlockState(%q) = %s", s, state)
+		}
+	}
+}
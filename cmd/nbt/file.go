@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// document is a root tag loaded from a file, along with what is needed to write it
+// back the way it was stored.
+type document struct {
+	name        string
+	root        interface{}
+	compression nbt.Compression
+	format      string
+}
+
+const (
+	formatBinary = "binary"
+	formatSNBT   = "snbt"
+	formatJSON   = "json"
+)
+
+// readDocument reads a binary NBT file, compressed with gzip or zlib or not at all,
+// or a text file holding SNBT or JSON written in the typed mode of nbt.ToJSON.
+func readDocument(r io.Reader) (*document, error) {
+	dr, compression, err := nbt.Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(dr)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{compression: compression}
+	text := bytes.TrimSpace(data)
+	switch {
+	case len(data) > 0 && data[0] == byte(nbt.TagCompound):
+		doc.format = formatBinary
+		doc.name, err = nbt.Unmarshal(data, reflect.ValueOf(&doc.root).Elem())
+	case bytes.HasPrefix(text, []byte(`{"type"`)):
+		doc.format = formatJSON
+		doc.root, err = nbt.FromJSON(text)
+	default:
+		doc.format = formatSNBT
+		doc.root, err = nbt.ParseSNBT(string(text))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func loadDocument(name string) (*document, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	doc, err := readDocument(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return doc, nil
+}
+
+// writeDocument writes the document in the given format, compressing binary output.
+func writeDocument(w io.Writer, doc *document, format string) error {
+	switch format {
+	case formatBinary:
+		cw := nbt.Compress(w, doc.compression)
+		if _, err := cw.Write(nbt.Marshal(doc.name, doc.root)); err != nil {
+			return err
+		}
+		return cw.Close()
+	case formatSNBT:
+		_, err := fmt.Fprintln(w, nbt.FormatSNBT(doc.root))
+		return err
+	case formatJSON:
+		data, err := nbt.ToJSON(doc.root, nbt.JSONTyped)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// saveDocument replaces the file name with the document in its original format. The
+// new content is written to a temporary file first and renamed over the original, so
+// the file is never left half written. If backup is set, the original content is
+// kept in name.bak.
+func saveDocument(name string, doc *document, backup bool) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = writeDocument(tmp, doc, doc.format); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return err
+	}
+
+	if backup {
+		original, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(name+".bak", original, info.Mode()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), name)
+}
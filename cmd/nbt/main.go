@@ -0,0 +1,214 @@
+// Command nbt inspects and edits NBT files.
+//
+// Usage:
+//
+//	nbt dump [-max-array n] [-max-depth n] <file>
+//	nbt get <file> <path>
+//	nbt set [-backup=false] <file> <path> <snbt>
+//	nbt rm [-backup=false] <file> <path>
+//	nbt convert [-to snbt|json|binary] [-compression gzip|zlib|none] [-o output] <file>
+//
+// Files may be binary NBT, compressed with gzip or zlib or not at all, SNBT, or JSON
+// in the typed form of nbt.ToJSON. Paths use the syntax of the /data command, such as
+// Inventory[{Slot:0b}].tag.display.Name.
+//
+// set and rm edit the file in place, keeping its format and compression. The new
+// content replaces the file atomically, and the original is kept in <file>.bak.
+//
+// convert writes binary output with the compression given by -compression, which is
+// gzip, zlib or none. It defaults to the compression of a binary input, and to gzip
+// when converting from SNBT or JSON.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"io"
+	"os"
+)
+
+const usage = `usage:
+	nbt dump [-max-array n] [-max-depth n] <file>
+	nbt get <file> <path>
+	nbt set [-backup=false] <file> <path> <snbt>
+	nbt rm [-backup=false] <file> <path>
+	nbt convert [-to snbt|json|binary] [-compression gzip|zlib|none] [-o output] <file>
+`
+
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	err := run(os.Args[1], os.Args[2:], os.Stdout)
+	if err == errUsage {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nbt:", err)
+		os.Exit(1)
+	}
+}
+
+func run(command string, args []string, stdout io.Writer) error {
+	switch command {
+	case "dump":
+		return runDump(args, stdout)
+	case "get":
+		return runGet(args, stdout)
+	case "set":
+		return runSet(args, stdout)
+	case "rm":
+		return runRemove(args, stdout)
+	case "convert":
+		return runConvert(args, stdout)
+	}
+	return errUsage
+}
+
+func runDump(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	maxArrayLen := flags.Int("max-array", 16, "number of array elements shown, 0 for all, -1 for none")
+	maxDepth := flags.Int("max-depth", 0, "number of levels shown, 0 for all")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	doc, err := loadDocument(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	opts := nbt.DumpOptions{Name: doc.name, MaxArrayLen: *maxArrayLen, MaxDepth: *maxDepth}
+	return opts.Dump(stdout, doc.root)
+}
+
+func runGet(args []string, stdout io.Writer) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+
+	doc, err := loadDocument(args[0])
+	if err != nil {
+		return err
+	}
+	path, err := nbt.ParsePath(args[1])
+	if err != nil {
+		return err
+	}
+
+	values := path.Get(doc.root)
+	if len(values) == 0 {
+		return fmt.Errorf("found no elements matching %s", path)
+	}
+	for _, value := range values {
+		fmt.Fprintln(stdout, nbt.FormatSNBT(value))
+	}
+	return nil
+}
+
+func runSet(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("set", flag.ContinueOnError)
+	backup := flags.Bool("backup", true, "keep the original file in <file>.bak")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 3 {
+		return errUsage
+	}
+
+	doc, err := loadDocument(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	path, err := nbt.ParsePath(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	value, err := nbt.ParseSNBT(flags.Arg(2))
+	if err != nil {
+		return err
+	}
+
+	changed, err := path.Set(doc.root, value)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "modified %d values\n", changed)
+	if changed == 0 {
+		return nil
+	}
+	return saveDocument(flags.Arg(0), doc, *backup)
+}
+
+func runRemove(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("rm", flag.ContinueOnError)
+	backup := flags.Bool("backup", true, "keep the original file in <file>.bak")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+
+	doc, err := loadDocument(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	path, err := nbt.ParsePath(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	removed, err := path.Remove(doc.root)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("found no elements matching %s", path)
+	}
+	fmt.Fprintf(stdout, "removed %d values\n", removed)
+	return saveDocument(flags.Arg(0), doc, *backup)
+}
+
+func runConvert(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("convert", flag.ContinueOnError)
+	to := flags.String("to", formatSNBT, "output format: snbt, json or binary")
+	output := flags.String("o", "", "output file (default stdout)")
+	compression := flags.String("compression", "", "compression of binary output: gzip, zlib or none (default: same as the input)")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	doc, err := loadDocument(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	switch *compression {
+	case "":
+		if doc.format != formatBinary {
+			doc.compression = nbt.CompressionGzip
+		}
+	case "gzip":
+		doc.compression = nbt.CompressionGzip
+	case "zlib":
+		doc.compression = nbt.CompressionZlib
+	case "none":
+		doc.compression = nbt.CompressionNone
+	default:
+		return errUsage
+	}
+
+	if *output == "" {
+		return writeDocument(stdout, doc, *to)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err = writeDocument(f, doc, *to); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
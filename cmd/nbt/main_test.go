@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/test"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeSample(t *testing.T, compression nbt.Compression) string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := nbt.Compress(buf, compression)
+	if _, err := w.Write(test.BigTestBytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	name := filepath.Join(t.TempDir(), "bigtest.nbt")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "scalar", path: "intTest", want: "2147483647\n"},
+		{name: "nested", path: `"nested compound test".egg.name`, want: "\"Eggbert\"\n"},
+		{name: "list", path: `"listTest (long)"[]`, want: "11L\n12L\n13L\n14L\n15L\n"},
+	}
+
+	name := writeSample(t, nbt.CompressionGzip)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := run("get", []string{name, tt.path}, out); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("get %s = %q, want %q", tt.path, out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAndRemove(t *testing.T) {
+	for _, compression := range []nbt.Compression{nbt.CompressionNone, nbt.CompressionGzip, nbt.CompressionZlib} {
+		t.Run(compression.String(), func(t *testing.T) {
+			name := writeSample(t, compression)
+			original, _ := os.ReadFile(name)
+
+			out := &bytes.Buffer{}
+			if err := run("set", []string{name, "intTest", "42"}, out); err != nil {
+				t.Fatal(err)
+			}
+			if err := run("rm", []string{"-backup=false", name, "byteTest"}, out); err != nil {
+				t.Fatal(err)
+			}
+
+			backup, err := os.ReadFile(name + ".bak")
+			if err != nil || !bytes.Equal(backup, original) {
+				t.Errorf("backup does not hold the original file")
+			}
+
+			doc, err := loadDocument(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.compression != compression || doc.name != "Level" {
+				t.Errorf("got %v root %q, want %v root %q", doc.compression, doc.name, compression, "Level")
+			}
+			root := doc.root.(map[string]interface{})
+			if root["intTest"] != int32(42) {
+				t.Errorf("intTest = %v, want 42", root["intTest"])
+			}
+			if _, ok := root["byteTest"]; ok {
+				t.Errorf("byteTest was not removed")
+			}
+		})
+	}
+}
+
+func TestRemoveMissing(t *testing.T) {
+	name := writeSample(t, nbt.CompressionGzip)
+	original, _ := os.ReadFile(name)
+
+	if err := run("rm", []string{name, "missing"}, &bytes.Buffer{}); err == nil {
+		t.Errorf("expected an error")
+	}
+	if current, _ := os.ReadFile(name); !bytes.Equal(current, original) {
+		t.Errorf("file was modified")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	name := writeSample(t, nbt.CompressionGzip)
+	want, err := loadDocument(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []string{formatSNBT, formatJSON, formatBinary} {
+		t.Run(format, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out")
+			if err := run("convert", []string{"-to", format, "-o", output, name}, &bytes.Buffer{}); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadDocument(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.format != format {
+				t.Errorf("detected %s, want %s", got.format, format)
+			}
+			if !reflect.DeepEqual(got.root, want.root) {
+				t.Errorf("converted document differs:\n%s", nbt.FormatDiff(nbt.Diff(want.root, got.root, nbt.DiffOptions{})))
+			}
+		})
+	}
+}
+
+func TestDump(t *testing.T) {
+	name := writeSample(t, nbt.CompressionNone)
+
+	out := &bytes.Buffer{}
+	if err := run("dump", []string{"-max-depth", "2", name}, out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"TAG_Compound('Level'): 11 entries\n",
+		"  TAG_Long('longTest'): 9223372036854775807\n",
+		"    TAG_Compound('egg'): 2 entries { ... }\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("dump does not contain %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// generator turns an inferred schema into Go struct definitions.
+type generator struct {
+	types  []string
+	bodies map[string]string
+	names  map[string]bool
+}
+
+// generate returns the formatted source of a Go file declaring typeName, the type of
+// the root compound described by s, along with every nested compound type.
+func generate(pkg string, typeName string, s *nbt.InferredSchema) ([]byte, error) {
+	g := &generator{bodies: make(map[string]string), names: make(map[string]bool)}
+	root := *s.Root
+	root.Count = s.Samples
+	g.structType(typeName, &root)
+
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "// Code generated by nbt2go. DO NOT EDIT.\n\npackage %s\n", pkg)
+	// Nested types are declared before their parents, so reverse to start at the root
+	for i := len(g.types) - 1; i >= 0; i-- {
+		src.WriteString("\n" + g.types[i])
+	}
+	return format.Source(src.Bytes())
+}
+
+// structType declares a struct for a compound node and returns its name. Compounds
+// with identical fields share a single type.
+func (g *generator) structType(name string, n *nbt.SchemaNode) string {
+	keys := make([]string, 0, len(n.Children))
+	for key := range n.Children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	body := &strings.Builder{}
+	fields := make(map[string]bool)
+	for _, key := range keys {
+		child := n.Children[key]
+
+		fieldName := uniqueName(exportedName(key), fields)
+		fieldType, isList := g.goType(name+fieldName, child)
+
+		// Optional keys are pointers, so a present zero value like Slot:0b is still
+		// written. Interfaces are nil when absent already.
+		tag := key
+		if child.Count < n.Count {
+			tag += ",omitempty"
+			if fieldType != "interface{}" {
+				fieldType = "*" + fieldType
+			}
+		}
+
+		fmt.Fprintf(body, "\t%s %s", fieldName, fieldType)
+		if tag != fieldName {
+			fmt.Fprintf(body, " `nbt:%s", strconv.Quote(tag))
+			if isList {
+				body.WriteString(` nbt_type:"list"`)
+			}
+			body.WriteString("`")
+		} else if isList {
+			body.WriteString(" `nbt_type:\"list\"`")
+		}
+		body.WriteString("\n")
+	}
+
+	if existing, ok := g.bodies[body.String()]; ok {
+		return existing
+	}
+
+	name = uniqueName(name, g.names)
+	g.bodies[body.String()] = name
+	g.types = append(g.types, fmt.Sprintf("type %s struct {\n%s}\n", name, body))
+	return name
+}
+
+// goType returns the Go type of a node, and whether it is a list that has to be
+// tagged with nbt_type:"list" to not be encoded as an array.
+func (g *generator) goType(name string, n *nbt.SchemaNode) (string, bool) {
+	if len(n.Types) != 1 {
+		return "interface{}", false
+	}
+
+	switch n.Type() {
+	case nbt.TagByte:
+		return "byte", false
+	case nbt.TagShort:
+		return "int16", false
+	case nbt.TagInt:
+		return "int32", false
+	case nbt.TagLong:
+		return "int64", false
+	case nbt.TagFloat:
+		return "float32", false
+	case nbt.TagDouble:
+		return "float64", false
+	case nbt.TagString:
+		return "string", false
+	case nbt.TagByteArray:
+		return "[]byte", false
+	case nbt.TagIntArray:
+		return "[]int32", false
+	case nbt.TagLongArray:
+		return "[]int64", false
+	case nbt.TagCompound:
+		return g.structType(name, n), false
+	case nbt.TagList:
+		if n.Elem == nil || len(n.Elem.Types) != 1 {
+			return "[]interface{}", false
+		}
+
+		switch n.Elem.Type() {
+		case nbt.TagByte, nbt.TagInt, nbt.TagLong:
+			elemType, _ := g.goType(name, n.Elem)
+			return "[]" + elemType, true
+		case nbt.TagList:
+			// Nested lists of bytes, ints or longs would be encoded as arrays, and
+			// struct tags cannot describe the inner list
+			if _, innerIsList := g.goType(name, n.Elem); innerIsList {
+				return "[]interface{}", false
+			}
+		}
+		elemType, _ := g.goType(name, n.Elem)
+		return "[]" + elemType, false
+	}
+	return "interface{}", false
+}
+
+// exportedName converts a tag name such as "created-on" or "listTest (long)" into an
+// exported Go identifier like CreatedOn or ListTestLong.
+func exportedName(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	sb := strings.Builder{}
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		sb.WriteString(string(runes))
+	}
+
+	name := sb.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "F" + name
+	}
+	return name
+}
+
+// uniqueName returns name, or name with a numeric suffix if it is already taken, and
+// marks the result as taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	taken[unique] = true
+	return unique
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/test"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		typeName string
+		samples  [][]byte
+		want     string
+	}{
+		{
+			name:     "bigtest",
+			typeName: "BigTest",
+			samples:  [][]byte{test.BigTestBytes},
+			want: "// Code generated by nbt2go. DO NOT EDIT.\n" +
+				"\n" +
+				"package test\n" +
+				"\n" +
+				"type BigTest struct {\n" +
+				"\tByteArrayTestTheFirst1000ValuesOfNN255N7100StartingWithN006234168 []byte                    `nbt:\"byteArrayTest (the first 1000 values of (n*n*255+n*7)%100, starting with n=0 (0, 62, 34, 16, 8, ...))\"`\n" +
+				"\tByteTest                                                          byte                      `nbt:\"byteTest\"`\n" +
+				"\tDoubleTest                                                        float64                   `nbt:\"doubleTest\"`\n" +
+				"\tFloatTest                                                         float32                   `nbt:\"floatTest\"`\n" +
+				"\tIntTest                                                           int32                     `nbt:\"intTest\"`\n" +
+				"\tListTestCompound                                                  []BigTestListTestCompound `nbt:\"listTest (compound)\"`\n" +
+				"\tListTestLong                                                      []int64                   `nbt:\"listTest (long)\" nbt_type:\"list\"`\n" +
+				"\tLongTest                                                          int64                     `nbt:\"longTest\"`\n" +
+				"\tNestedCompoundTest                                                BigTestNestedCompoundTest `nbt:\"nested compound test\"`\n" +
+				"\tShortTest                                                         int16                     `nbt:\"shortTest\"`\n" +
+				"\tStringTest                                                        string                    `nbt:\"stringTest\"`\n" +
+				"}\n" +
+				"\n" +
+				"type BigTestNestedCompoundTest struct {\n" +
+				"\tEgg BigTestNestedCompoundTestEgg `nbt:\"egg\"`\n" +
+				"\tHam BigTestNestedCompoundTestEgg `nbt:\"ham\"`\n" +
+				"}\n" +
+				"\n" +
+				"type BigTestNestedCompoundTestEgg struct {\n" +
+				"\tName  string  `nbt:\"name\"`\n" +
+				"\tValue float32 `nbt:\"value\"`\n" +
+				"}\n" +
+				"\n" +
+				"type BigTestListTestCompound struct {\n" +
+				"\tCreatedOn int64  `nbt:\"created-on\"`\n" +
+				"\tName      string `nbt:\"name\"`\n" +
+				"}\n",
+		},
+		{
+			name:     "optional keys and arrays",
+			typeName: "Root",
+			samples: [][]byte{
+				nbt.Marshal("", map[string]interface{}{
+					"Name":  "a",
+					"UUID":  []int32{1, 2, 3, 4},
+					"Slots": []interface{}{int32(1)},
+					"Mixed": int32(1),
+				}),
+				nbt.Marshal("", map[string]interface{}{
+					"UUID":   []int32{1, 2, 3, 4},
+					"Slots":  []interface{}{},
+					"Mixed":  "b",
+					"Nested": []interface{}{[]interface{}{int32(1)}},
+					"Owner":  map[string]interface{}{"Name": "c"},
+				}),
+			},
+			want: "// Code generated by nbt2go. DO NOT EDIT.\n" +
+				"\n" +
+				"package test\n" +
+				"\n" +
+				"type Root struct {\n" +
+				"\tMixed  interface{}\n" +
+				"\tName   *string        `nbt:\"Name,omitempty\"`\n" +
+				"\tNested *[]interface{} `nbt:\"Nested,omitempty\"`\n" +
+				"\tOwner  *RootOwner     `nbt:\"Owner,omitempty\"`\n" +
+				"\tSlots  []int32        `nbt_type:\"list\"`\n" +
+				"\tUUID   []int32\n" +
+				"}\n" +
+				"\n" +
+				"type RootOwner struct {\n" +
+				"\tName string\n" +
+				"}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := nbt.NewInferredSchema()
+			for _, sample := range tt.samples {
+				if err := s.Add(bytes.NewReader(sample)); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+
+			got, err := generate("test", tt.typeName, s)
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddSampleSNBT(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "sample.snbt")
+	_ = os.WriteFile(name, []byte(`{name:"Bananrama"}`), 0644)
+
+	s := nbt.NewInferredSchema()
+	if err := addSample(s, name); err != nil {
+		t.Fatalf("addSample() error = %v", err)
+	}
+	if s.Root.Children["name"].Type() != nbt.TagString {
+		t.Errorf("got %+v", s.Root.Children["name"])
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	tests := map[string]string{
+		"created-on":      "CreatedOn",
+		"listTest (long)": "ListTestLong",
+		"xPos":            "XPos",
+		"1st":             "F1st",
+		"---":             "Field",
+	}
+
+	for input, want := range tests {
+		if got := exportedName(input); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Command nbt2go generates Go struct definitions from NBT or SNBT samples.
+//
+// Usage:
+//
+//	nbt2go [-package name] [-type name] [-o file] sample...
+//
+// Every sample is walked to infer the schema of its root compound. Keys missing from
+// some samples become pointer fields tagged omitempty, so they are only written when
+// set. Lists of bytes, ints and longs are tagged nbt_type:"list" so they are not
+// encoded as arrays.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	pkg := flag.String("package", "main", "package of the generated file")
+	typeName := flag.String("type", "Root", "name of the root struct")
+	output := flag.String("o", "", "output file (default stdout)")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: nbt2go [-package name] [-type name] [-o file] sample...")
+		os.Exit(2)
+	}
+
+	s := nbt.NewInferredSchema()
+	for _, name := range flag.Args() {
+		if err := addSample(s, name); err != nil {
+			fmt.Fprintln(os.Stderr, "nbt2go:", err)
+			os.Exit(1)
+		}
+	}
+
+	src, err := generate(*pkg, *typeName, s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nbt2go:", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*output, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "nbt2go:", err)
+		os.Exit(1)
+	}
+}
+
+// addSample records a binary NBT file, or an SNBT file if it has the .snbt extension.
+func addSample(s *nbt.InferredSchema, name string) error {
+	if !strings.EqualFold(filepath.Ext(name), ".snbt") {
+		return s.AddFile(name)
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	tree, err := nbt.ParseSNBT(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return s.Add(bytes.NewReader(nbt.Marshal("", tree)))
+}
@@ -3,37 +3,73 @@ package nbt
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"io"
 	"math"
 	"reflect"
+	"sort"
+	"strings"
 )
 
-type namedTagType byte
+// TagType identifies the type of a named binary tag as it appears on the wire.
+type TagType byte
 
 const (
-	tagEnd namedTagType = iota
-	tagByte
-	tagShort
-	tagInt
-	tagLong
-	tagFloat
-	tagDouble
-	tagByteArray
-	tagString
-	tagList
-	tagCompound
-	tagIntArray
-	tagLongArray
-	tagNone = 0xFF
+	TagEnd TagType = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+	TagNone TagType = 0xFF
 )
 
-func readTagType(buf *bytes.Buffer) (t namedTagType, err error) {
-	tb, err := buf.ReadByte()
-	return namedTagType(tb), err
+var tagTypeNames = map[TagType]string{
+	TagEnd:       "TAG_End",
+	TagByte:      "TAG_Byte",
+	TagShort:     "TAG_Short",
+	TagInt:       "TAG_Int",
+	TagLong:      "TAG_Long",
+	TagFloat:     "TAG_Float",
+	TagDouble:    "TAG_Double",
+	TagByteArray: "TAG_Byte_Array",
+	TagString:    "TAG_String",
+	TagList:      "TAG_List",
+	TagCompound:  "TAG_Compound",
+	TagIntArray:  "TAG_Int_Array",
+	TagLongArray: "TAG_Long_Array",
 }
 
-func readUInt16(buf *bytes.Buffer) (uint16, error) {
+func (t TagType) String() string {
+	if name, ok := tagTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TAG_Unknown(%d)", byte(t))
+}
+
+// reader is the minimal interface the decoding primitives need. Both *bytes.Buffer
+// and *bufio.Reader satisfy it, allowing the same code to decode in-memory data
+// and streams.
+type reader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func readTagType(r reader) (t TagType, err error) {
+	tb, err := r.ReadByte()
+	return TagType(tb), err
+}
+
+func readUInt16(r reader) (uint16, error) {
 	b := make([]byte, 2)
-	n, err := buf.Read(b)
+	n, err := io.ReadFull(r, b)
 	if err != nil {
 		return 0, err
 	}
@@ -46,17 +82,17 @@ func readUInt16(buf *bytes.Buffer) (uint16, error) {
 	return uint16(b[0])<<8 | uint16(b[1]), nil
 }
 
-func readInt16(buf *bytes.Buffer) (int16, error) {
-	uv, err := readUInt16(buf)
+func readInt16(r reader) (int16, error) {
+	uv, err := readUInt16(r)
 	if err != nil {
 		return 0, err
 	}
 	return int16(uv), nil
 }
 
-func readUInt32(buf *bytes.Buffer) (uint32, error) {
+func readUInt32(r reader) (uint32, error) {
 	b := make([]byte, 4)
-	n, err := buf.Read(b)
+	n, err := io.ReadFull(r, b)
 	if err != nil {
 		return 0, err
 	}
@@ -69,17 +105,17 @@ func readUInt32(buf *bytes.Buffer) (uint32, error) {
 	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]), nil
 }
 
-func readInt32(buf *bytes.Buffer) (int32, error) {
-	v, err := readUInt32(buf)
+func readInt32(r reader) (int32, error) {
+	v, err := readUInt32(r)
 	if err != nil {
 		return 0, err
 	}
 	return int32(v), nil
 }
 
-func readUInt64(buf *bytes.Buffer) (uint64, error) {
+func readUInt64(r reader) (uint64, error) {
 	b := make([]byte, 8)
-	n, err := buf.Read(b)
+	n, err := io.ReadFull(r, b)
 	if err != nil {
 		return 0, err
 	}
@@ -92,78 +128,99 @@ func readUInt64(buf *bytes.Buffer) (uint64, error) {
 	return uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 | uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7]), nil
 }
 
-func readInt64(buf *bytes.Buffer) (int64, error) {
-	v, err := readUInt64(buf)
+func readInt64(r reader) (int64, error) {
+	v, err := readUInt64(r)
 	if err != nil {
 		return 0, err
 	}
 	return int64(v), nil
 }
 
-func readFloat32(buf *bytes.Buffer) (float32, error) {
-	v, err := readUInt32(buf)
+func readFloat32(r reader) (float32, error) {
+	v, err := readUInt32(r)
 	if err != nil {
 		return 0, err
 	}
 	return math.Float32frombits(v), nil
 }
 
-func readFloat64(buf *bytes.Buffer) (float64, error) {
-	v, err := readUInt64(buf)
+func readFloat64(r reader) (float64, error) {
+	v, err := readUInt64(r)
 	if err != nil {
 		return 0, err
 	}
 	return math.Float64frombits(v), nil
 }
 
-func readByteSlice(buf *bytes.Buffer) ([]byte, error) {
-	length, err := readInt32(buf)
+// arrayChunk is the number of array elements allocated ahead of the data, so a
+// corrupt length fails at the end of input rather than exhausting memory.
+const arrayChunk = 1 << 16
+
+func readArrayLength(r reader) (int, error) {
+	length, err := readInt32(r)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	v := make([]byte, length, length)
-	readBytes, err := buf.Read(v)
-	if readBytes > int(length) {
-		return v, errors.New("read too many bytes")
+	if length < 0 {
+		return 0, errors.New("negative length")
 	}
-	if readBytes < int(length) {
-		return v, errors.New("read too few bytes")
+	return int(length), nil
+}
+
+func readByteSlice(r reader) ([]byte, error) {
+	length, err := readArrayLength(r)
+	if err != nil {
+		return nil, err
 	}
-	return v, nil
+	buf := bytes.NewBuffer(make([]byte, 0, min(length, arrayChunk)))
+	n, err := io.CopyN(buf, r, int64(length))
+	if n < int64(length) {
+		return buf.Bytes(), errors.New("read too few bytes")
+	}
+	return buf.Bytes(), err
 }
 
-func readInt32Slice(buf *bytes.Buffer) ([]int32, error) {
-	length, err := readInt32(buf)
+func readInt32Slice(r reader) ([]int32, error) {
+	length, err := readArrayLength(r)
 	if err != nil {
 		return nil, err
 	}
-	v := make([]int32, length, length)
-	for i:=0; i<int(length); i++ {
-		v[i], err = readInt32(buf)
+	v := make([]int32, 0, min(length, arrayChunk))
+	for i := 0; i < length; i++ {
+		e, err := readInt32(r)
 		if err != nil {
 			return v, err
 		}
+		v = append(v, e)
 	}
 	return v, nil
 }
 
-func readInt64Slice(buf *bytes.Buffer) ([]int64, error) {
-	length, err := readInt32(buf)
+func readInt64Slice(r reader) ([]int64, error) {
+	length, err := readArrayLength(r)
 	if err != nil {
 		return nil, err
 	}
-	v := make([]int64, length, length)
-	for i:=0; i<int(length); i++ {
-		v[i], err = readInt64(buf)
+	v := make([]int64, 0, min(length, arrayChunk))
+	for i := 0; i < length; i++ {
+		e, err := readInt64(r)
 		if err != nil {
 			return v, err
 		}
+		v = append(v, e)
 	}
 	return v, nil
 }
 
-func readString(buf *bytes.Buffer) (string, error) {
-	length, err := readUInt16(buf)
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func readString(r reader) (string, error) {
+	length, err := readUInt16(r)
 	if err != nil {
 		return "", err
 	}
@@ -172,7 +229,7 @@ func readString(buf *bytes.Buffer) (string, error) {
 	}
 
 	v := make([]byte, length, length)
-	readBytes, err := buf.Read(v)
+	readBytes, err := io.ReadFull(r, v)
 	if err != nil {
 		return "", err
 	}
@@ -185,7 +242,7 @@ func readString(buf *bytes.Buffer) (string, error) {
 	return string(v), nil
 }
 
-func writeTagType(t namedTagType) []byte {
+func writeTagType(t TagType) []byte {
 	return []byte{byte(t)}
 }
 
@@ -255,8 +312,11 @@ func writeList(v reflect.Value) []byte {
 	buf := &bytes.Buffer{}
 
 	nestedTagType := typeOf(v.Type().Elem())
+	if v.Type().Elem().Kind() == reflect.Interface && v.Len() > 0 {
+		nestedTagType = typeOf(reflect.TypeOf(v.Index(0).Interface()))
+	}
 	if v.Len() <= 0 {
-		nestedTagType = tagEnd // Mimic notchian behavior
+		nestedTagType = TagEnd // Mimic notchian behavior
 	}
 	buf.Write(writeTagType(nestedTagType))
 
@@ -269,20 +329,24 @@ func writeList(v reflect.Value) []byte {
 
 func writeCompound(value interface{}) []byte {
 	if value == nil {
-		return writeTagType(tagEnd)
+		return writeTagType(TagEnd)
 	}
 
 	buf := &bytes.Buffer{}
 
 	v := reflect.ValueOf(value)
 	if v.Type().Kind() == reflect.Map {
-		mapRange := v.MapRange()
-		for mapRange.Next() {
-			nestedTagType := typeOf(reflect.TypeOf(mapRange.Value().Interface()))
+		// Sort keys so the same map always encodes to the same bytes
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		for _, key := range keys {
+			nestedValue := v.MapIndex(key).Interface()
+			nestedTagType := typeOf(reflect.TypeOf(nestedValue))
 
 			buf.Write(writeTagType(nestedTagType))
-			buf.Write(writeString(mapRange.Key().String()))
-			buf.Write(writeValue(nestedTagType, mapRange.Value().Interface()))
+			buf.Write(writeString(key.String()))
+			buf.Write(writeValue(nestedTagType, nestedValue))
 		}
 	} else {
 		numFields := v.NumField()
@@ -290,21 +354,32 @@ func writeCompound(value interface{}) []byte {
 		for i := 0; i < numFields; i++ {
 			f := v.Type().Field(i)
 
-			nestedTagName := f.Tag.Get("nbt")
-
-			// Take the field name if unspecified
-			if nestedTagName == "" {
-				nestedTagName = f.Name
+			// Ignore unexported fields
+			if f.PkgPath != "" {
+				continue
 			}
 
+			nestedTagName, omitEmpty := parseFieldTag(f)
+
 			// Ignore unwanted tags
 			if nestedTagName == "-" {
 				continue
 			}
 
+			if omitEmpty && isEmptyValue(v.Field(i)) {
+				continue
+			}
+
 			nestedTagType := typeOf(f.Type)
+			if kind := f.Type.Kind(); kind == reflect.Interface || kind == reflect.Ptr {
+				// Nil interfaces and pointers are left out
+				if v.Field(i).IsNil() {
+					continue
+				}
+				nestedTagType = typeOf(v.Field(i).Elem().Type())
+			}
 			if f.Tag.Get("nbt_type") == "list" {
-				nestedTagType = tagList
+				nestedTagType = TagList
 			}
 
 			isPresent := true
@@ -331,6 +406,43 @@ func writeCompound(value interface{}) []byte {
 		}
 	}
 
-	buf.Write(writeTagType(tagEnd))
+	buf.Write(writeTagType(TagEnd))
 	return buf.Bytes()
 }
+
+// parseFieldTag returns the tag name of a struct field, taken from the nbt struct tag
+// or the field name if unspecified, and whether the omitempty option is set. Tag
+// names may contain commas, so only a trailing ",omitempty" is treated as an option.
+func parseFieldTag(f reflect.StructField) (name string, omitEmpty bool) {
+	name = f.Tag.Get("nbt")
+	if strings.HasSuffix(name, ",omitempty") {
+		name = strings.TrimSuffix(name, ",omitempty")
+		omitEmpty = true
+	}
+
+	// Take the field name if unspecified
+	if name == "" {
+		name = f.Name
+	}
+	return
+}
+
+// isEmptyValue reports whether a field tagged omitempty should be left out: false,
+// zero numbers, nil interfaces and pointers, and empty strings, slices and maps.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
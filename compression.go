@@ -0,0 +1,67 @@
+package nbt
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// Compression is the compression applied to a whole NBT file.
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionGzip
+	CompressionZlib
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZlib:
+		return "zlib"
+	}
+	return "none"
+}
+
+// Decompress detects whether r holds gzip, zlib or uncompressed data and returns a
+// reader for the uncompressed data along with the detected compression.
+func Decompress(r io.Reader) (io.Reader, Compression, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, CompressionNone, err
+	}
+
+	switch {
+	case len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		zr, err := gzip.NewReader(br)
+		return zr, CompressionGzip, err
+	case len(magic) == 2 && magic[0] == 0x78 && (uint16(magic[0])<<8|uint16(magic[1]))%31 == 0:
+		zr, err := zlib.NewReader(br)
+		return zr, CompressionZlib, err
+	}
+	return br, CompressionNone, nil
+}
+
+// Compress returns a writer that compresses data written to it into w. Closing it
+// flushes the compressed stream but does not close w.
+func Compress(w io.Writer, c Compression) io.WriteCloser {
+	switch c {
+	case CompressionGzip:
+		return gzip.NewWriter(w)
+	case CompressionZlib:
+		return zlib.NewWriter(w)
+	}
+	return nopWriteCloser{w}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt/test"
+	"io"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZlib} {
+		t.Run(c.String(), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			w := Compress(buf, c)
+			_, _ = w.Write(test.BigTestBytes)
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			r, got, err := Decompress(buf)
+			if err != nil {
+				t.Fatalf("Decompress() error = %v", err)
+			}
+			if got != c {
+				t.Errorf("detected %v, want %v", got, c)
+			}
+
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			if !bytes.Equal(data, test.BigTestBytes) {
+				t.Errorf("data not equal")
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package nbt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind classifies a Change.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+	TypeChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	case TypeChanged:
+		return "type changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a single difference reported by Diff. Old is nil for added entries and
+// New is nil for removed ones.
+type Change struct {
+	Kind ChangeKind
+	Path Path
+	Old  interface{}
+	New  interface{}
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, FormatSNBT(c.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, FormatSNBT(c.Old))
+	case TypeChanged:
+		return fmt.Sprintf("! %s: %v -> %v\n- %s: %s\n+ %s: %s", c.Path, tagTypeOf(c.Old), tagTypeOf(c.New),
+			c.Path, FormatSNBT(c.Old), c.Path, FormatSNBT(c.New))
+	}
+	return fmt.Sprintf("- %s: %s\n+ %s: %s", c.Path, FormatSNBT(c.Old), c.Path, FormatSNBT(c.New))
+}
+
+// DiffOptions controls how Diff compares values.
+type DiffOptions struct {
+	// FloatTolerance is the largest difference between two floats or doubles that is
+	// still considered equal.
+	FloatTolerance float64
+
+	// IgnoreListOrder compares lists as multisets, reporting only elements that have
+	// no equal counterpart in the other list.
+	IgnoreListOrder bool
+}
+
+// Diff compares two documents and returns every added, removed and changed entry,
+// addressed by its NBT path. Entries whose tag type differs are reported as
+// TypeChanged rather than Changed. Arrays are compared as single values.
+func Diff(a interface{}, b interface{}, opts DiffOptions) []Change {
+	d := &differ{opts: opts}
+	d.diff(Path{}, treeOf(a), treeOf(b))
+	return d.changes
+}
+
+// FormatDiff formats changes as unified-diff-like text, one line per removed (-) or
+// added (+) value, with type changes flagged by a line starting with '!'.
+func FormatDiff(changes []Change) string {
+	sb := strings.Builder{}
+	for _, c := range changes {
+		sb.WriteString(c.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+type differ struct {
+	opts    DiffOptions
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path Path, old interface{}, new interface{}) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Old: old, New: new})
+}
+
+func (d *differ) diff(path Path, a interface{}, b interface{}) {
+	if tagTypeOf(a) != tagTypeOf(b) {
+		d.add(TypeChanged, path, a, b)
+		return
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b := b.(map[string]interface{})
+
+		keys := make([]string, 0, len(a)+len(b))
+		for key := range a {
+			keys = append(keys, key)
+		}
+		for key := range b {
+			if _, ok := a[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			va, inA := a[key]
+			vb, inB := b[key]
+			switch {
+			case !inB:
+				d.add(Removed, path.child(key), va, nil)
+			case !inA:
+				d.add(Added, path.child(key), nil, vb)
+			default:
+				d.diff(path.child(key), va, vb)
+			}
+		}
+	case []interface{}:
+		if d.opts.IgnoreListOrder {
+			d.diffUnordered(path, a, b.([]interface{}))
+		} else {
+			d.diffOrdered(path, a, b.([]interface{}))
+		}
+	default:
+		if !d.equal(a, b) {
+			d.add(Changed, path, a, b)
+		}
+	}
+}
+
+func (d *differ) diffOrdered(path Path, a []interface{}, b []interface{}) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		switch {
+		case i >= len(b):
+			d.add(Removed, path.element(i), a[i], nil)
+		case i >= len(a):
+			d.add(Added, path.element(i), nil, b[i])
+		default:
+			d.diff(path.element(i), a[i], b[i])
+		}
+	}
+}
+
+func (d *differ) diffUnordered(path Path, a []interface{}, b []interface{}) {
+	matched := make([]bool, len(b))
+	for i, va := range a {
+		found := false
+		for j, vb := range b {
+			if !matched[j] && d.equal(va, vb) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			d.add(Removed, path.element(i), va, nil)
+		}
+	}
+	for j, vb := range b {
+		if !matched[j] {
+			d.add(Added, path.element(j), nil, vb)
+		}
+	}
+}
+
+// equal reports whether two values are equal, taking the float tolerance and list
+// order options into account.
+func (d *differ) equal(a interface{}, b interface{}) bool {
+	if d.opts.FloatTolerance == 0 && !d.opts.IgnoreListOrder {
+		return reflect.DeepEqual(a, b)
+	}
+
+	nested := &differ{opts: d.opts}
+	switch a := a.(type) {
+	case float32:
+		b, ok := b.(float32)
+		return ok && math.Abs(float64(a)-float64(b)) <= d.opts.FloatTolerance
+	case float64:
+		b, ok := b.(float64)
+		return ok && math.Abs(a-b) <= d.opts.FloatTolerance
+	case map[string]interface{}, []interface{}:
+		nested.diff(Path{}, a, b)
+		return len(nested.changes) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
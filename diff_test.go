@@ -0,0 +1,87 @@
+package nbt
+
+import (
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		opts DiffOptions
+		want string
+	}{
+		{
+			name: "equal",
+			a:    `{a:1,b:[1,2],c:{d:"e"}}`,
+			b:    `{c:{d:"e"},b:[1,2],a:1}`,
+			want: "",
+		},
+		{
+			name: "added removed and changed",
+			a:    `{a:1,b:2,c:{d:"e"}}`,
+			b:    `{a:1,c:{d:"f"},g:3b}`,
+			want: "- b: 2\n" +
+				"- c.d: \"e\"\n+ c.d: \"f\"\n" +
+				"+ g: 3b\n",
+		},
+		{
+			name: "type change",
+			a:    `{Time:1}`,
+			b:    `{Time:1L}`,
+			want: "! Time: TAG_Int -> TAG_Long\n- Time: 1\n+ Time: 1L\n",
+		},
+		{
+			name: "list elements",
+			a:    `{Tags:["a","b"]}`,
+			b:    `{Tags:["a","c","d"]}`,
+			want: "- Tags[1]: \"b\"\n+ Tags[1]: \"c\"\n" +
+				"+ Tags[2]: \"d\"\n",
+		},
+		{
+			name: "ignore list order",
+			a:    `{Tags:["a","b"],Items:[{id:1},{id:2}]}`,
+			b:    `{Tags:["b","a","c"],Items:[{id:2},{id:1}]}`,
+			opts: DiffOptions{IgnoreListOrder: true},
+			want: "+ Tags[2]: \"c\"\n",
+		},
+		{
+			name: "float tolerance",
+			a:    `{Pos:[1.0d,2.0d],Health:20.0f}`,
+			b:    `{Pos:[1.0000001d,2.5d],Health:19.99999f}`,
+			opts: DiffOptions{FloatTolerance: 0.001},
+			want: "- Pos[1]: 2.0d\n+ Pos[1]: 2.5d\n",
+		},
+		{
+			name: "arrays",
+			a:    `{UUID:[I;1,2,3,4]}`,
+			b:    `{UUID:[I;1,2,3,5]}`,
+			want: "- UUID: [I;1,2,3,4]\n+ UUID: [I;1,2,3,5]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := Diff(mustParseSNBT(t, tt.a), mustParseSNBT(t, tt.b), tt.opts)
+			if got := FormatDiff(changes); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	changes := Diff(mustParseSNBT(t, `{a:{b:1}}`), mustParseSNBT(t, `{a:{b:1s}}`), DiffOptions{})
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+
+	c := changes[0]
+	if c.Kind != TypeChanged || c.Path.String() != "a.b" || c.Old != int32(1) || c.New != int16(1) {
+		t.Errorf("got %+v", c)
+	}
+	if got := MustParsePath("a.b").Get(mustParseSNBT(t, `{a:{b:1}}`)); len(got) != 1 || got[0] != c.Old {
+		t.Errorf("change path does not resolve to the old value")
+	}
+}
@@ -0,0 +1,136 @@
+package nbt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DumpOptions controls the output of Dump.
+type DumpOptions struct {
+	// Name is the name of the root tag.
+	Name string
+
+	// MaxArrayLen is the number of array elements shown before the rest is elided.
+	// Zero shows every element and a negative value shows only the length.
+	MaxArrayLen int
+
+	// MaxDepth is the number of levels of compounds and lists whose entries are shown,
+	// the root being the first. Zero shows every level.
+	MaxDepth int
+}
+
+// Dump writes data as the indented tree printed by NBTExplorer and the reference
+// implementation. Compound entries are listed in sorted order.
+//
+//	TAG_Compound('Level'): 2 entries
+//	{
+//	  TAG_Long('longTest'): 9223372036854775807
+//	  TAG_List('listTest (long)'): 2 entries
+//	  {
+//	    TAG_Long(None): 11
+//	    TAG_Long(None): 12
+//	  }
+//	}
+func Dump(w io.Writer, data interface{}) error {
+	return DumpOptions{}.Dump(w, data)
+}
+
+// Dump writes data like the package level Dump, applying the options.
+func (o DumpOptions) Dump(w io.Writer, data interface{}) error {
+	tree, err := toTree(data)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	o.dump(bw, "'"+o.Name+"'", tree, 0)
+	return bw.Flush()
+}
+
+func (o DumpOptions) dump(w *bufio.Writer, name string, value interface{}, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(w, "%s%v(%s): ", indent, tagTypeOf(value), name)
+
+	var entries []string
+	var values []interface{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		entries = make([]string, 0, len(v))
+		for key := range v {
+			entries = append(entries, key)
+		}
+		sort.Strings(entries)
+		values = make([]interface{}, len(entries))
+		for i, key := range entries {
+			values[i] = v[key]
+			entries[i] = "'" + key + "'"
+		}
+	case []interface{}:
+		entries = make([]string, len(v))
+		for i := range v {
+			entries[i] = "None"
+		}
+		values = v
+	default:
+		w.WriteString(o.formatValue(value))
+		w.WriteByte('\n')
+		return
+	}
+
+	fmt.Fprintf(w, "%d entries", len(entries))
+	if o.MaxDepth > 0 && depth >= o.MaxDepth && len(entries) > 0 {
+		w.WriteString(" { ... }\n")
+		return
+	}
+	w.WriteString("\n" + indent + "{\n")
+	for i := range entries {
+		o.dump(w, entries[i], values[i], depth+1)
+	}
+	w.WriteString(indent + "}\n")
+}
+
+func (o DumpOptions) formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case byte:
+		return strconv.Itoa(int(int8(v)))
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	case []byte:
+		elements := make([]string, len(v))
+		for i, b := range v {
+			elements[i] = strconv.Itoa(int(int8(b)))
+		}
+		return o.formatArray(elements, "bytes")
+	case []int32:
+		elements := make([]string, len(v))
+		for i, n := range v {
+			elements[i] = strconv.Itoa(int(n))
+		}
+		return o.formatArray(elements, "ints")
+	case []int64:
+		elements := make([]string, len(v))
+		for i, n := range v {
+			elements[i] = strconv.FormatInt(n, 10)
+		}
+		return o.formatArray(elements, "longs")
+	}
+	return fmt.Sprint(value)
+}
+
+func (o DumpOptions) formatArray(elements []string, unit string) string {
+	s := fmt.Sprintf("[%d %s]", len(elements), unit)
+	switch {
+	case o.MaxArrayLen < 0 || len(elements) == 0:
+		return s
+	case o.MaxArrayLen > 0 && len(elements) > o.MaxArrayLen:
+		return s + " " + strings.Join(elements[:o.MaxArrayLen], ", ") + ", ..."
+	}
+	return s + " " + strings.Join(elements, ", ")
+}
@@ -0,0 +1,130 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt/test"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	tests := []struct {
+		name string
+		opts DumpOptions
+		data interface{}
+		want string
+	}{
+		{
+			name: "scalars",
+			opts: DumpOptions{Name: "root"},
+			data: map[string]interface{}{"b": byte(0xff), "s": int16(5), "str": "hi there", "f": float32(0.5)},
+			want: "TAG_Compound('root'): 4 entries\n" +
+				"{\n" +
+				"  TAG_Byte('b'): -1\n" +
+				"  TAG_Float('f'): 0.5\n" +
+				"  TAG_Short('s'): 5\n" +
+				"  TAG_String('str'): hi there\n" +
+				"}\n",
+		},
+		{
+			name: "unnamed root",
+			data: map[string]interface{}{},
+			want: "TAG_Compound(''): 0 entries\n{\n}\n",
+		},
+		{
+			name: "list",
+			data: map[string]interface{}{"l": []interface{}{int32(1), int32(2)}},
+			want: "TAG_Compound(''): 1 entries\n" +
+				"{\n" +
+				"  TAG_List('l'): 2 entries\n" +
+				"  {\n" +
+				"    TAG_Int(None): 1\n" +
+				"    TAG_Int(None): 2\n" +
+				"  }\n" +
+				"}\n",
+		},
+		{
+			name: "arrays",
+			data: map[string]interface{}{"b": []byte{1, 0xfe}, "i": []int32{1, 2, 3}, "l": []int64{}},
+			want: "TAG_Compound(''): 3 entries\n" +
+				"{\n" +
+				"  TAG_Byte_Array('b'): [2 bytes] 1, -2\n" +
+				"  TAG_Int_Array('i'): [3 ints] 1, 2, 3\n" +
+				"  TAG_Long_Array('l'): [0 longs]\n" +
+				"}\n",
+		},
+		{
+			name: "truncated arrays",
+			opts: DumpOptions{MaxArrayLen: 2},
+			data: map[string]interface{}{"i": []int32{1, 2, 3}, "l": []int64{4, 5}},
+			want: "TAG_Compound(''): 2 entries\n" +
+				"{\n" +
+				"  TAG_Int_Array('i'): [3 ints] 1, 2, ...\n" +
+				"  TAG_Long_Array('l'): [2 longs] 4, 5\n" +
+				"}\n",
+		},
+		{
+			name: "array lengths only",
+			opts: DumpOptions{MaxArrayLen: -1},
+			data: map[string]interface{}{"i": []int32{1, 2, 3}},
+			want: "TAG_Compound(''): 1 entries\n{\n  TAG_Int_Array('i'): [3 ints]\n}\n",
+		},
+		{
+			name: "max depth",
+			opts: DumpOptions{Name: "Level", MaxDepth: 2},
+			data: map[string]interface{}{
+				"nested": map[string]interface{}{"egg": map[string]interface{}{"name": "Eggbert"}, "empty": map[string]interface{}{}},
+			},
+			want: "TAG_Compound('Level'): 1 entries\n" +
+				"{\n" +
+				"  TAG_Compound('nested'): 2 entries\n" +
+				"  {\n" +
+				"    TAG_Compound('egg'): 1 entries { ... }\n" +
+				"    TAG_Compound('empty'): 0 entries\n" +
+				"    {\n" +
+				"    }\n" +
+				"  }\n" +
+				"}\n",
+		},
+		{
+			name: "struct",
+			opts: DumpOptions{MaxDepth: 1},
+			data: test.BananramaStruct,
+			want: "TAG_Compound(''): 1 entries\n{\n  TAG_String('name'): Bananrama\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := tt.opts.Dump(buf, tt.data); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Dump() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpBigTest(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := Dump(buf, bigTestTree(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 46 {
+		t.Errorf("got %d lines, want 46", len(lines))
+	}
+	for _, line := range []string{
+		"TAG_Compound(''): 11 entries",
+		"  TAG_Long('longTest'): 9223372036854775807",
+		"      TAG_String('name'): Eggbert",
+		"    TAG_Long(None): 15",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("dump does not contain %q", line)
+		}
+	}
+}
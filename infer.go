@@ -0,0 +1,278 @@
+package nbt
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SchemaNode collects what was observed at one path across all samples.
+type SchemaNode struct {
+	// Count is the number of times the path was seen. For list elements it counts
+	// every element of every list.
+	Count int
+
+	// Types counts the occurrences of each tag type. More than one entry means the
+	// type of the path is not stable across samples.
+	Types map[TagType]int
+
+	// Min and Max hold the range of numeric values.
+	Min float64
+	Max float64
+
+	// MinLen and MaxLen hold the range of lengths of strings, lists and arrays.
+	MinLen int
+	MaxLen int
+
+	// Children holds the entries of compounds, and Elem the elements of lists.
+	Children map[string]*SchemaNode
+	Elem     *SchemaNode
+}
+
+func newSchemaNode() *SchemaNode {
+	return &SchemaNode{
+		Types:  make(map[TagType]int),
+		Min:    math.Inf(1),
+		Max:    math.Inf(-1),
+		MinLen: math.MaxInt32,
+		MaxLen: -1,
+	}
+}
+
+// Type returns the most frequently observed tag type of the node.
+func (n *SchemaNode) Type() TagType {
+	best := TagEnd
+	for t, count := range n.Types {
+		if count > n.Types[best] || count == n.Types[best] && t < best {
+			best = t
+		}
+	}
+	return best
+}
+
+// HasRange reports whether numeric values were observed.
+func (n *SchemaNode) HasRange() bool {
+	return n.Min <= n.Max
+}
+
+// HasLen reports whether strings, lists or arrays were observed.
+func (n *SchemaNode) HasLen() bool {
+	return n.MinLen <= n.MaxLen
+}
+
+// InferredSchema describes the structure of a set of NBT samples: every path seen,
+// its tag types, how often it appears and the range of its values.
+type InferredSchema struct {
+	Samples int
+	Root    *SchemaNode
+}
+
+func NewInferredSchema() *InferredSchema {
+	return &InferredSchema{Root: newSchemaNode()}
+}
+
+// Add reads one root tag from r and records it in the schema. The tag is walked with
+// a Decoder, so samples are never fully materialized.
+func (s *InferredSchema) Add(r io.Reader) error {
+	d := NewDecoder(r)
+	tok, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := tok.(End); ok {
+		return nil
+	}
+
+	if err = s.observe(d, tok, s.Root); err != nil {
+		return err
+	}
+	s.Samples++
+	return nil
+}
+
+// AddFile records the root tag of a gzip, zlib or uncompressed NBT file.
+func (s *InferredSchema) AddFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, _, err := Decompress(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err = s.Add(r); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// AddDir records every file below dir whose extension is in exts, such as ".dat" or
+// ".nbt".
+func (s *InferredSchema) AddDir(dir string, exts ...string) error {
+	return filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		for _, ext := range exts {
+			if strings.EqualFold(filepath.Ext(name), ext) {
+				return s.AddFile(name)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *InferredSchema) observe(d *Decoder, tok Token, n *SchemaNode) error {
+	n.Count++
+
+	switch tok := tok.(type) {
+	case CompoundStart:
+		n.Types[TagCompound]++
+		if n.Children == nil {
+			n.Children = make(map[string]*SchemaNode)
+		}
+		for {
+			child, err := d.Token()
+			if err != nil {
+				return err
+			}
+			if _, ok := child.(End); ok {
+				return nil
+			}
+
+			name := tokenName(child)
+			if n.Children[name] == nil {
+				n.Children[name] = newSchemaNode()
+			}
+			if err = s.observe(d, child, n.Children[name]); err != nil {
+				return err
+			}
+		}
+	case ListStart:
+		n.Types[TagList]++
+		n.observeLen(tok.Len)
+		if n.Elem == nil {
+			n.Elem = newSchemaNode()
+		}
+		for i := 0; i < tok.Len; i++ {
+			child, err := d.Token()
+			if err != nil {
+				return err
+			}
+			if err = s.observe(d, child, n.Elem); err != nil {
+				return err
+			}
+		}
+		_, err := d.Token()
+		return err
+	case Value:
+		n.Types[tok.Type]++
+		switch v := tok.Value.(type) {
+		case byte:
+			n.observeValue(float64(int8(v)))
+		case int16:
+			n.observeValue(float64(v))
+		case int32:
+			n.observeValue(float64(v))
+		case int64:
+			n.observeValue(float64(v))
+		case float32:
+			n.observeValue(float64(v))
+		case float64:
+			n.observeValue(v)
+		case string:
+			n.observeLen(len(v))
+		case []byte:
+			n.observeLen(len(v))
+		case []int32:
+			n.observeLen(len(v))
+		case []int64:
+			n.observeLen(len(v))
+		}
+	}
+	return nil
+}
+
+func (n *SchemaNode) observeValue(v float64) {
+	n.Min = math.Min(n.Min, v)
+	n.Max = math.Max(n.Max, v)
+}
+
+func (n *SchemaNode) observeLen(length int) {
+	if length < n.MinLen {
+		n.MinLen = length
+	}
+	if length > n.MaxLen {
+		n.MaxLen = length
+	}
+}
+
+// Walk calls fn for every path in the schema, parents before children and compound
+// entries in sorted order. List elements are addressed with [].
+func (s *InferredSchema) Walk(fn func(path Path, node *SchemaNode)) {
+	walkSchemaNode(Path{}, s.Root, nil, func(path Path, n *SchemaNode, parent *SchemaNode) {
+		fn(path, n)
+	})
+}
+
+func walkSchemaNode(path Path, n *SchemaNode, parent *SchemaNode, fn func(path Path, n *SchemaNode, parent *SchemaNode)) {
+	if parent != nil {
+		fn(path, n, parent)
+	}
+
+	keys := make([]string, 0, len(n.Children))
+	for key := range n.Children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		walkSchemaNode(path.child(key), n.Children[key], n, fn)
+	}
+	if n.Elem != nil && n.Elem.Count > 0 {
+		walkSchemaNode(path.all(), n.Elem, n, fn)
+	}
+}
+
+// WriteTo writes a report of every path in the schema, one per line. Compound
+// entries show how often they were seen relative to their compound.
+func (s *InferredSchema) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	var err error
+	walkSchemaNode(Path{}, s.Root, nil, func(path Path, n *SchemaNode, parent *SchemaNode) {
+		if err != nil {
+			return
+		}
+
+		types := make([]string, 0, len(n.Types))
+		for t := range n.Types {
+			types = append(types, t.String())
+		}
+		sort.Strings(types)
+
+		line := fmt.Sprintf("%s\t%s\tseen %d", path, strings.Join(types, "|"), n.Count)
+		if n != parent.Elem {
+			line += fmt.Sprintf("/%d", parent.Count)
+		}
+		if n.HasRange() {
+			line += fmt.Sprintf("\trange [%g, %g]", n.Min, n.Max)
+		}
+		if n.HasLen() {
+			line += fmt.Sprintf("\tlength [%d, %d]", n.MinLen, n.MaxLen)
+		}
+		if n.Elem != nil && len(n.Elem.Types) > 0 {
+			line += fmt.Sprintf("\telements %v", n.Elem.Type())
+		}
+
+		var nn int
+		nn, err = fmt.Fprintln(w, line)
+		written += int64(nn)
+	})
+	return written, err
+}
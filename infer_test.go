@@ -0,0 +1,72 @@
+package nbt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/junglemc/nbt/test"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInferredSchema(t *testing.T) {
+	s := NewInferredSchema()
+	for _, sample := range [][]byte{test.BigTestBytes, test.UnnamedRootCompoundBytes} {
+		if err := s.Add(bytes.NewReader(sample)); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	if s.Samples != 2 {
+		t.Errorf("got %d samples, want 2", s.Samples)
+	}
+
+	longTest := s.Root.Children["longTest"]
+	if longTest.Count != 1 || longTest.Type() != TagLong || longTest.Max != 9223372036854775807 {
+		t.Errorf("longTest got %+v", longTest)
+	}
+
+	list := s.Root.Children["listTest (compound)"]
+	if list.Type() != TagList || list.MinLen != 2 || list.Elem.Count != 2 || list.Elem.Type() != TagCompound {
+		t.Errorf("listTest (compound) got %+v", list)
+	}
+	if createdOn := list.Elem.Children["created-on"]; createdOn.Count != 2 || createdOn.Min != 1264099775885 {
+		t.Errorf("created-on got %+v", createdOn)
+	}
+
+	report := &strings.Builder{}
+	if _, err := s.WriteTo(report); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	for _, want := range []string{
+		"ByteTag\tTAG_Byte\tseen 1/2\trange [-1, -1]\n",
+		"\"listTest (compound)\"[].name\tTAG_String\tseen 2/2\tlength [15, 15]\n",
+		"\"listTest (long)\"\tTAG_List\tseen 1/2\tlength [5, 5]\telements TAG_Long\n",
+		"\"listTest (long)\"[]\tTAG_Long\tseen 5\trange [11, 15]\n",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestInferredSchemaAddDir(t *testing.T) {
+	dir := t.TempDir()
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	_, _ = zw.Write(test.BananramaBytes)
+	_ = zw.Close()
+	_ = os.WriteFile(filepath.Join(dir, "a.dat"), buf.Bytes(), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "b.dat"), test.BananramaBytes, 0644)
+	_ = os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("not nbt"), 0644)
+
+	s := NewInferredSchema()
+	if err := s.AddDir(dir, ".dat"); err != nil {
+		t.Fatalf("AddDir() error = %v", err)
+	}
+	if s.Samples != 2 || s.Root.Children["name"].Count != 2 {
+		t.Errorf("got %d samples, %+v", s.Samples, s.Root.Children["name"])
+	}
+}
@@ -0,0 +1,339 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// JSONMode selects how ToJSON represents tags.
+type JSONMode int
+
+const (
+	// JSONTyped wraps every tag in an object holding its type and value, such as
+	// {"type":"short","value":5}. Longs are written as strings so no precision is lost,
+	// and the result can be converted back with FromJSON.
+	JSONTyped JSONMode = iota
+
+	// JSONPlain maps tags to their natural JSON counterparts. Tag types are lost and
+	// longs beyond 2^53 lose precision, so it is only suitable for display.
+	JSONPlain
+)
+
+var jsonTypeNames = map[TagType]string{
+	TagByte:      "byte",
+	TagShort:     "short",
+	TagInt:       "int",
+	TagLong:      "long",
+	TagFloat:     "float",
+	TagDouble:    "double",
+	TagByteArray: "byte_array",
+	TagString:    "string",
+	TagList:      "list",
+	TagCompound:  "compound",
+	TagIntArray:  "int_array",
+	TagLongArray: "long_array",
+}
+
+// ToJSON converts a value to JSON in the given mode.
+func ToJSON(v interface{}, mode JSONMode) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err = writeJSON(buf, tree, mode); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}, mode JSONMode) error {
+	tagType := tagTypeOf(v)
+	if mode == JSONTyped {
+		name, ok := jsonTypeNames[tagType]
+		if !ok {
+			return fmt.Errorf("json: cannot convert %T", v)
+		}
+		buf.WriteString(`{"type":"` + name + `","value":`)
+		defer buf.WriteByte('}')
+	}
+
+	switch v := v.(type) {
+	case byte:
+		buf.WriteString(strconv.Itoa(int(int8(v))))
+	case int16:
+		buf.WriteString(strconv.Itoa(int(v)))
+	case int32:
+		buf.WriteString(strconv.Itoa(int(v)))
+	case int64:
+		if mode == JSONTyped {
+			buf.WriteString(`"` + strconv.FormatInt(v, 10) + `"`)
+		} else {
+			buf.WriteString(strconv.FormatInt(v, 10))
+		}
+	case float32:
+		writeJSONFloat(buf, float64(v), 32)
+	case float64:
+		writeJSONFloat(buf, v, 64)
+	case string:
+		s, _ := json.Marshal(v)
+		buf.Write(s)
+	case []byte:
+		buf.WriteByte('[')
+		for i, b := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Itoa(int(int8(b))))
+		}
+		buf.WriteByte(']')
+	case []int32:
+		buf.WriteByte('[')
+		for i, n := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(strconv.Itoa(int(n)))
+		}
+		buf.WriteByte(']')
+	case []int64:
+		buf.WriteByte('[')
+		for i, n := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if mode == JSONTyped {
+				buf.WriteString(`"` + strconv.FormatInt(n, 10) + `"`)
+			} else {
+				buf.WriteString(strconv.FormatInt(n, 10))
+			}
+		}
+		buf.WriteByte(']')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, nested := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, nested, mode); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			k, _ := json.Marshal(key)
+			buf.Write(k)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, v[key], mode); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("json: cannot convert %T", v)
+	}
+	return nil
+}
+
+// writeJSONFloat writes the shortest representation that parses back to the same
+// float. JSON has no NaN or infinities, so those are written as strings.
+func writeJSONFloat(buf *bytes.Buffer, f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		buf.WriteString(`"NaN"`)
+	case math.IsInf(f, 1):
+		buf.WriteString(`"Infinity"`)
+	case math.IsInf(f, -1):
+		buf.WriteString(`"-Infinity"`)
+	default:
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+}
+
+// FromJSON converts JSON written by ToJSON in JSONTyped mode back to a generic tree.
+func FromJSON(data []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var node interface{}
+	if err := d.Decode(&node); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("json: unexpected data after the root tag")
+	}
+	return fromJSON(node)
+}
+
+func fromJSON(node interface{}) (interface{}, error) {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("json: expected a typed tag object, got %v", node)
+	}
+	typeName, _ := obj["type"].(string)
+	value, ok := obj["value"]
+	if !ok {
+		return nil, fmt.Errorf("json: tag of type %q has no value", typeName)
+	}
+
+	switch typeName {
+	case "byte":
+		n, err := jsonInt(value, 8)
+		return byte(n), err
+	case "short":
+		n, err := jsonInt(value, 16)
+		return int16(n), err
+	case "int":
+		n, err := jsonInt(value, 32)
+		return int32(n), err
+	case "long":
+		return jsonInt(value, 64)
+	case "float":
+		f, err := jsonFloat(value, 32)
+		return float32(f), err
+	case "double":
+		return jsonFloat(value, 64)
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("json: invalid string %v", value)
+		}
+		return s, nil
+	case "byte_array", "int_array", "long_array":
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json: invalid %s %v", typeName, value)
+		}
+		return jsonArray(typeName, elements)
+	case "list":
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json: invalid list %v", value)
+		}
+		list := make([]interface{}, len(elements))
+		for i, element := range elements {
+			v, err := fromJSON(element)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 && tagTypeOf(v) != tagTypeOf(list[0]) {
+				return nil, fmt.Errorf("json: cannot insert %v into a list of %v", tagTypeOf(v), tagTypeOf(list[0]))
+			}
+			list[i] = v
+		}
+		return list, nil
+	case "compound":
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("json: invalid compound %v", value)
+		}
+		m := make(map[string]interface{}, len(entries))
+		for key, entry := range entries {
+			v, err := fromJSON(entry)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("json: unknown tag type %q", typeName)
+}
+
+func jsonArray(typeName string, elements []interface{}) (interface{}, error) {
+	switch typeName {
+	case "byte_array":
+		array := make([]byte, len(elements))
+		for i, element := range elements {
+			n, err := jsonInt(element, 8)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = byte(n)
+		}
+		return array, nil
+	case "int_array":
+		array := make([]int32, len(elements))
+		for i, element := range elements {
+			n, err := jsonInt(element, 32)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = int32(n)
+		}
+		return array, nil
+	default:
+		array := make([]int64, len(elements))
+		for i, element := range elements {
+			n, err := jsonInt(element, 64)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = n
+		}
+		return array, nil
+	}
+}
+
+// jsonInt parses an integer given as a JSON number or string, checking it fits in
+// bitSize bits.
+func jsonInt(value interface{}, bitSize int) (int64, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return 0, fmt.Errorf("json: invalid integer %v", value)
+	}
+
+	n, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("json: invalid %d-bit integer %q", bitSize, s)
+	}
+	return n, nil
+}
+
+func jsonFloat(value interface{}, bitSize int) (float64, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return 0, fmt.Errorf("json: invalid float %v", value)
+	}
+
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	f, err := strconv.ParseFloat(s, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("json: invalid float %q", s)
+	}
+	return f, nil
+}
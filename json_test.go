@@ -0,0 +1,126 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		mode  JSONMode
+		want  string
+	}{
+		{
+			name:  "typed",
+			input: `{b:-1b,s:5s,l:9223372036854775807L,f:0.5f,list:[1,2],bytes:[B;1b,-2b],longs:[L;1L]}`,
+			mode:  JSONTyped,
+			want: `{"type":"compound","value":{` +
+				`"b":{"type":"byte","value":-1},` +
+				`"bytes":{"type":"byte_array","value":[1,-2]},` +
+				`"f":{"type":"float","value":0.5},` +
+				`"l":{"type":"long","value":"9223372036854775807"},` +
+				`"list":{"type":"list","value":[{"type":"int","value":1},{"type":"int","value":2}]},` +
+				`"longs":{"type":"long_array","value":["1"]},` +
+				`"s":{"type":"short","value":5}}}`,
+		},
+		{
+			name:  "plain",
+			input: `{b:-1b,s:5s,l:9223372036854775807L,f:0.5f,list:[1,2],name:"a\"b",nested:{ints:[I;1,2]}}`,
+			mode:  JSONPlain,
+			want:  `{"b":-1,"f":0.5,"l":9223372036854775807,"list":[1,2],"name":"a\"b","nested":{"ints":[1,2]},"s":5}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToJSON(mustParseSNBT(t, tt.input), tt.mode)
+			if err != nil {
+				t.Fatalf("ToJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tree := bigTestTree(t)
+
+	data, err := ToJSON(tree, JSONTyped)
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+
+	got, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, interface{}(tree)) {
+		t.Errorf("tags not equal")
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "long as number",
+			input: `{"type":"long","value":5}`,
+			want:  int64(5),
+		},
+		{
+			name:    "byte out of range",
+			input:   `{"type":"byte","value":200}`,
+			wantErr: true,
+		},
+		{
+			name:    "mixed list",
+			input:   `{"type":"list","value":[{"type":"int","value":1},{"type":"byte","value":1}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			input:   `{"type":"uuid","value":1}`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			input:   `{"type":"int","value":1} {"type":"int","value":2}`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing garbage",
+			input:   `{"type":"int","value":1}]`,
+			wantErr: true,
+		},
+		{
+			name:  "trailing whitespace",
+			input: "{\"type\":\"int\",\"value\":1}\n",
+			want:  int32(1),
+		},
+		{
+			name:    "untyped",
+			input:   `{"a":1}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromJSON([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
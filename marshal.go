@@ -6,9 +6,9 @@ import (
 )
 
 func Marshal(tagName string, value interface{}) []byte {
-	var tagType namedTagType
+	var tagType TagType
 	if value == nil {
-		tagType = tagCompound
+		tagType = TagCompound
 	} else {
 		tagType = typeOf(reflect.TypeOf(value))
 	}
@@ -20,11 +20,17 @@ func Marshal(tagName string, value interface{}) []byte {
 	return buf.Bytes()
 }
 
-func writeValue(tagType namedTagType, value interface{}) []byte {
+func writeValue(tagType TagType, value interface{}) []byte {
 	v := reflect.ValueOf(value)
 
+	// Pointers are encoded as the value they point to
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+		value = v.Interface()
+	}
+
 	switch tagType {
-	case tagByte:
+	case TagByte:
 		if reflect.TypeOf(value).Kind() == reflect.Bool {
 			if v.Bool() {
 				return writeByte(1)
@@ -33,62 +39,64 @@ func writeValue(tagType namedTagType, value interface{}) []byte {
 			}
 		}
 		return writeByte(byte(v.Uint()))
-	case tagShort:
+	case TagShort:
 		return writeInt16(int16(v.Int()))
-	case tagInt:
+	case TagInt:
 		return writeInt32(int32(v.Int()))
-	case tagLong:
+	case TagLong:
 		return writeInt64(v.Int())
-	case tagFloat:
+	case TagFloat:
 		return writeFloat32(float32(v.Float()))
-	case tagDouble:
+	case TagDouble:
 		return writeFloat64(v.Float())
-	case tagString:
+	case TagString:
 		return writeString(v.String())
-	case tagList:
+	case TagList:
 		return writeList(v)
-	case tagCompound:
+	case TagCompound:
 		return writeCompound(value)
-	case tagByteArray:
+	case TagByteArray:
 		return writeByteSlice(v.Bytes())
-	case tagIntArray:
+	case TagIntArray:
 		return writeInt32Slice(v)
-	case tagLongArray:
+	case TagLongArray:
 		return writeInt64Slice(v)
 	}
 	return nil
 }
 
-func typeOf(t reflect.Type) namedTagType {
+func typeOf(t reflect.Type) TagType {
 	switch t.Kind() {
 	case reflect.Uint8, reflect.Bool:
-		return tagByte
+		return TagByte
 	case reflect.Int16, reflect.Uint16:
-		return tagShort
+		return TagShort
 	case reflect.Int32, reflect.Uint32:
-		return tagInt
+		return TagInt
 	case reflect.Float32:
-		return tagFloat
+		return TagFloat
 	case reflect.Int64, reflect.Uint64:
-		return tagLong
+		return TagLong
 	case reflect.Float64:
-		return tagDouble
+		return TagDouble
 	case reflect.String:
-		return tagString
+		return TagString
 	case reflect.Struct, reflect.Interface, reflect.Map:
-		return tagCompound
+		return TagCompound
+	case reflect.Ptr:
+		return typeOf(t.Elem())
 	case reflect.Array, reflect.Slice:
 		switch t.Elem().Kind() {
 		case reflect.Uint8:
-			return tagByteArray
+			return TagByteArray
 		case reflect.Int32:
-			return tagIntArray
+			return TagIntArray
 		case reflect.Int64:
-			return tagLongArray
+			return TagLongArray
 		default:
-			return tagList
+			return TagList
 		}
 	default:
-		return tagNone
+		return TagNone
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/junglemc/nbt/test"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -71,6 +72,20 @@ func TestMarshalCompoundStruct(t *testing.T) {
 			want:    test.BananramaBytes,
 			wantErr: false,
 		},
+		{
+			name:    "omitempty",
+			tagName: "hello world",
+			tag:     test.BananramaOmitEmpty{Name: "Bananrama"},
+			want:    test.BananramaBytes,
+			wantErr: false,
+		},
+		{
+			name:    "nil pointers",
+			tagName: "hello world",
+			tag:     test.BananramaOptional{Name: &test.BananramaStruct.Name},
+			want:    test.BananramaBytes,
+			wantErr: false,
+		},
 		{
 			name:    "bigtest",
 			tagName: "Level",
@@ -126,3 +141,54 @@ func TestMarshalCompoundStruct(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalInterfaceRoundTrip(t *testing.T) {
+	var tree interface{}
+	if _, err := Unmarshal(test.BigTestBytes, reflect.ValueOf(&tree).Elem()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	var got interface{}
+	if _, err := Unmarshal(Marshal("Level", tree), reflect.ValueOf(&got).Elem()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(tree, got) {
+		t.Errorf("tags not equal")
+	}
+}
+
+func TestMarshalInterfaceField(t *testing.T) {
+	want := test.BananramaOmitEmpty{
+		Name:  "Bananrama",
+		Extra: []interface{}{int32(1), int32(2)},
+	}
+
+	got := test.BananramaOmitEmpty{}
+	if _, err := Unmarshal(Marshal("", want), reflect.ValueOf(&got).Elem()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestMarshalPointerFields(t *testing.T) {
+	name, count := "Bananrama", int32(0)
+	tests := []test.BananramaOptional{
+		{},
+		{Name: &name, Count: &count},
+		{Owner: &test.Bananrama{Name: "Bananrama"}},
+	}
+
+	for _, want := range tests {
+		got := test.BananramaOptional{}
+		if _, err := Unmarshal(Marshal("", want), reflect.ValueOf(&got).Elem()); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
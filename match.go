@@ -0,0 +1,82 @@
+package nbt
+
+import (
+	"reflect"
+)
+
+// Matches reports whether data partially matches pattern, the way target selectors
+// (@e[nbt={OnGround:1b}]) and predicates test entity and block data:
+//
+//   - a compound matches if every key of the pattern is present and matches
+//   - a list matches if every pattern element matches some element, and an empty
+//     pattern list only matches an empty list
+//   - anything else, including arrays, matches if it is of the same tag type and equal
+//
+// Both data and pattern may be generic trees as produced by Unmarshal and ParseSNBT,
+// or any value accepted by Marshal. It returns an error for values Marshal cannot
+// encode.
+func Matches(data interface{}, pattern interface{}) (bool, error) {
+	d, err := toTree(data)
+	if err != nil {
+		return false, err
+	}
+	p, err := toTree(pattern)
+	if err != nil {
+		return false, err
+	}
+	return matches(d, p), nil
+}
+
+// MatchesSNBT is like Matches, with the pattern given as stringified NBT.
+func MatchesSNBT(data interface{}, pattern string) (bool, error) {
+	p, err := ParseSNBT(pattern)
+	if err != nil {
+		return false, err
+	}
+	d, err := toTree(data)
+	if err != nil {
+		return false, err
+	}
+	return matches(d, p), nil
+}
+
+func matches(data interface{}, pattern interface{}) bool {
+	switch pattern := pattern.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, nested := range pattern {
+			value, ok := m[key]
+			if !ok || !matches(value, nested) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		list, ok := data.([]interface{})
+		if !ok {
+			return false
+		}
+		if len(pattern) == 0 {
+			return len(list) == 0
+		}
+		for _, nested := range pattern {
+			found := false
+			for _, value := range list {
+				if matches(value, nested) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(data, pattern)
+}
@@ -0,0 +1,83 @@
+package nbt
+
+import (
+	"testing"
+)
+
+func TestMatchesSNBT(t *testing.T) {
+	entity := map[string]interface{}{
+		"OnGround": byte(1),
+		"Health":   float32(20),
+		"Tags":     []interface{}{"a", "b", "c"},
+		"UUID":     []int32{1, 2, 3, 4},
+		"Inventory": []interface{}{
+			map[string]interface{}{"Slot": byte(0), "id": "minecraft:stone", "Count": byte(64)},
+			map[string]interface{}{"Slot": byte(1), "id": "minecraft:dirt", "Count": byte(1)},
+		},
+		"Passengers": []interface{}{},
+	}
+
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{pattern: `{}`, want: true},
+		{pattern: `{OnGround:1b}`, want: true},
+		{pattern: `{OnGround:0b}`, want: false},
+		{pattern: `{OnGround:1}`, want: false},
+		{pattern: `{Health:20.0f}`, want: true},
+		{pattern: `{Health:20}`, want: false},
+		{pattern: `{Missing:1b}`, want: false},
+		{pattern: `{Tags:["c","a"]}`, want: true},
+		{pattern: `{Tags:["a","a"]}`, want: true},
+		{pattern: `{Tags:["d"]}`, want: false},
+		{pattern: `{Tags:[]}`, want: false},
+		{pattern: `{Passengers:[]}`, want: true},
+		{pattern: `{UUID:[I;1,2,3,4]}`, want: true},
+		{pattern: `{UUID:[I;1,2,3]}`, want: false},
+		{pattern: `{Inventory:[{id:"minecraft:dirt"}]}`, want: true},
+		{pattern: `{Inventory:[{id:"minecraft:dirt",Count:64b}]}`, want: false},
+		{pattern: `{Inventory:[{Slot:0b},{Slot:1b}]}`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := MatchesSNBT(entity, tt.pattern)
+			if err != nil {
+				t.Fatalf("MatchesSNBT() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchesStruct(t *testing.T) {
+	type entity struct {
+		OnGround bool
+		Health   float32
+	}
+
+	tests := []struct {
+		data    interface{}
+		pattern interface{}
+		want    bool
+	}{
+		{data: entity{OnGround: true, Health: 20}, pattern: map[string]interface{}{"OnGround": byte(1)}, want: true},
+		{data: entity{OnGround: false, Health: 20}, pattern: map[string]interface{}{"OnGround": true}, want: false},
+	}
+	for _, tt := range tests {
+		got, err := Matches(tt.data, tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("Matches(%+v, %v) = %v, want %v", tt.data, tt.pattern, got, tt.want)
+		}
+	}
+
+	if _, err := Matches(entity{}, map[string]interface{}{"Health": 20}); err == nil {
+		t.Errorf("expected an error matching a pattern holding an int")
+	}
+}
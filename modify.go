@@ -0,0 +1,122 @@
+package nbt
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Merge merges src into dst with the semantics of the /data merge command: compounds
+// present in both are merged recursively, while every other value, including lists
+// and arrays, is replaced by a copy of the one in src.
+func Merge(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		nestedSrc, srcIsCompound := value.(map[string]interface{})
+		nestedDst, dstIsCompound := dst[key].(map[string]interface{})
+		if srcIsCompound && dstIsCompound {
+			Merge(nestedDst, nestedSrc)
+			continue
+		}
+		dst[key] = copyTree(value)
+	}
+}
+
+// Merge merges the compound value into every compound the path matches in root,
+// creating missing compounds along the way. It returns the number of compounds that
+// changed.
+func (p Path) Merge(root interface{}, value interface{}) (int, error) {
+	tree, err := toTree(value)
+	if err != nil {
+		return 0, err
+	}
+	src, ok := tree.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("path: cannot merge %v, expected %v", tagTypeOf(tree), TagCompound)
+	}
+
+	changed := 0
+	for _, slot := range p.resolve(root, newCompound) {
+		dst, ok := slot.get().(map[string]interface{})
+		if !ok {
+			return changed, fmt.Errorf("path: cannot merge into %v", tagTypeOf(slot.get()))
+		}
+
+		before := copyTree(dst)
+		Merge(dst, src)
+		if !reflect.DeepEqual(before, dst) {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// Insert inserts a copy of value at index into every list or array the path matches
+// in root, creating a missing list at the end of the path. A negative index counts
+// from the end, so -1 appends. It returns the number of lists that changed.
+func (p Path) Insert(root interface{}, index int, value interface{}) (int, error) {
+	value, err := toTree(value)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, slot := range p.resolve(root, func() interface{} { return make([]interface{}, 0) }) {
+		list, err := insertElement(slot.get(), index, copyTree(value))
+		if err != nil {
+			return changed, err
+		}
+		if err = slot.set(list); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// Append adds a copy of value to the end of every list or array the path matches.
+func (p Path) Append(root interface{}, value interface{}) (int, error) {
+	return p.Insert(root, -1, value)
+}
+
+// Prepend adds a copy of value to the start of every list or array the path matches.
+func (p Path) Prepend(root interface{}, value interface{}) (int, error) {
+	return p.Insert(root, 0, value)
+}
+
+// insertElement returns a copy of list with value inserted at index. Arrays accept
+// any numeric value, converting it to their element type like vanilla does.
+func insertElement(list interface{}, index int, value interface{}) (interface{}, error) {
+	listType := tagTypeOf(list)
+	switch listType {
+	case TagList, TagByteArray, TagIntArray, TagLongArray:
+	default:
+		return nil, fmt.Errorf("path: cannot insert into %v", listType)
+	}
+
+	l := reflect.ValueOf(list)
+	if index < 0 {
+		index += l.Len() + 1
+	}
+	if index < 0 || index > l.Len() {
+		return nil, fmt.Errorf("path: index %d out of bounds for length %d", index, l.Len())
+	}
+
+	element := reflect.ValueOf(value)
+	if listType == TagList {
+		if l.Len() > 0 && tagTypeOf(value) != tagTypeOf(l.Index(0).Interface()) {
+			return nil, fmt.Errorf("path: cannot insert %v into a list of %v", tagTypeOf(value), tagTypeOf(l.Index(0).Interface()))
+		}
+	} else {
+		switch tagTypeOf(value) {
+		case TagByte, TagShort, TagInt, TagLong, TagFloat, TagDouble:
+			element = element.Convert(l.Type().Elem())
+		default:
+			return nil, fmt.Errorf("path: cannot insert %v into %v", tagTypeOf(value), listType)
+		}
+	}
+
+	inserted := reflect.MakeSlice(l.Type(), 0, l.Len()+1)
+	inserted = reflect.AppendSlice(inserted, l.Slice(0, index))
+	inserted = reflect.Append(inserted, element)
+	inserted = reflect.AppendSlice(inserted, l.Slice(index, l.Len()))
+	return inserted.Interface(), nil
+}
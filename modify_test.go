@@ -0,0 +1,224 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func mustParseSNBT(t *testing.T, s string) interface{} {
+	v, err := ParseSNBT(s)
+	if err != nil {
+		t.Fatalf("ParseSNBT(%s) error = %v", s, err)
+	}
+	return v
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		dst  string
+		src  string
+		want string
+	}{
+		{
+			name: "recursive compound merge",
+			dst:  `{id:"minecraft:diamond_sword",tag:{Damage:5,display:{Name:"a"}}}`,
+			src:  `{tag:{display:{Lore:["b"]},Unbreakable:1b}}`,
+			want: `{id:"minecraft:diamond_sword",tag:{Damage:5,Unbreakable:1b,display:{Lore:["b"],Name:"a"}}}`,
+		},
+		{
+			name: "lists and arrays are replaced",
+			dst:  `{Tags:["a","b"],UUID:[I;1,2,3,4]}`,
+			src:  `{Tags:["c"],UUID:[I;5,6,7,8]}`,
+			want: `{Tags:["c"],UUID:[I;5,6,7,8]}`,
+		},
+		{
+			name: "type change",
+			dst:  `{a:{b:1}}`,
+			src:  `{a:1b}`,
+			want: `{a:1b}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := mustParseSNBT(t, tt.dst).(map[string]interface{})
+			Merge(dst, mustParseSNBT(t, tt.src).(map[string]interface{}))
+
+			if got := FormatSNBT(dst); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathModify(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		modify  func(root interface{}) (int, error)
+		want    string
+		changed int
+		wantErr bool
+	}{
+		{
+			name: "merge",
+			root: `{Inventory:[{Slot:0b,tag:{a:1}},{Slot:1b}]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Inventory[{Slot:0b}].tag`).Merge(root, mustParseSNBT(t, `{b:2}`))
+			},
+			want:    `{Inventory:[{Slot:0b,tag:{a:1,b:2}},{Slot:1b}]}`,
+			changed: 1,
+		},
+		{
+			name: "merge creates missing compounds",
+			root: `{}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`tag.display`).Merge(root, mustParseSNBT(t, `{Name:"x"}`))
+			},
+			want:    `{tag:{display:{Name:"x"}}}`,
+			changed: 1,
+		},
+		{
+			name: "merge without changes",
+			root: `{tag:{a:1}}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`tag`).Merge(root, mustParseSNBT(t, `{a:1}`))
+			},
+			want:    `{tag:{a:1}}`,
+			changed: 0,
+		},
+		{
+			name: "append",
+			root: `{Tags:["a"]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Append(root, "b")
+			},
+			want:    `{Tags:["a","b"]}`,
+			changed: 1,
+		},
+		{
+			name: "prepend",
+			root: `{Tags:["a"]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Prepend(root, "b")
+			},
+			want:    `{Tags:["b","a"]}`,
+			changed: 1,
+		},
+		{
+			name: "insert",
+			root: `{Tags:["a","c"]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Insert(root, 1, "b")
+			},
+			want:    `{Tags:["a","b","c"]}`,
+			changed: 1,
+		},
+		{
+			name: "insert creates missing list",
+			root: `{}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Append(root, "a")
+			},
+			want:    `{Tags:["a"]}`,
+			changed: 1,
+		},
+		{
+			name: "insert into array converts",
+			root: `{UUID:[I;1,2]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`UUID`).Append(root, byte(3))
+			},
+			want:    `{UUID:[I;1,2,3]}`,
+			changed: 1,
+		},
+		{
+			name: "insert wrong type",
+			root: `{Tags:["a"]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Append(root, int32(1))
+			},
+			wantErr: true,
+		},
+		{
+			name: "insert out of bounds",
+			root: `{Tags:["a"]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Insert(root, 3, "b")
+			},
+			wantErr: true,
+		},
+		{
+			name: "set int",
+			root: `{a:{}}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`a.b`).Set(root, 5)
+			},
+			wantErr: true,
+		},
+		{
+			name: "set compound holding nil",
+			root: `{a:{}}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`a`).Set(root, map[string]interface{}{"b": nil})
+			},
+			wantErr: true,
+		},
+		{
+			name: "set list of mixed types",
+			root: `{a:{}}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`a`).Set(root, []interface{}{int32(1), "b"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "merge struct with int field",
+			root: `{a:{}}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`a`).Merge(root, struct{ B int }{5})
+			},
+			wantErr: true,
+		},
+		{
+			name: "insert int",
+			root: `{Tags:[]}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Append(root, 5)
+			},
+			wantErr: true,
+		},
+		{
+			name: "insert into compound",
+			root: `{Tags:{}}`,
+			modify: func(root interface{}) (int, error) {
+				return MustParsePath(`Tags`).Append(root, "a")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := mustParseSNBT(t, tt.root)
+			changed, err := tt.modify(root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if got, want := FormatSNBT(root), FormatSNBT(mustParseSNBT(t, tt.root)); got != want {
+					t.Errorf("root changed to %s despite the error", got)
+				}
+				return
+			}
+
+			if changed != tt.changed {
+				t.Errorf("changed %d, want %d", changed, tt.changed)
+			}
+			if got := FormatSNBT(root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
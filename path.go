@@ -0,0 +1,548 @@
+package nbt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type pathNodeKind int
+
+const (
+	pathKey       pathNodeKind = iota // foo
+	pathKeyMatch                      // foo{bar:1b}
+	pathIndex                         // [0]
+	pathAll                           // []
+	pathListMatch                     // [{bar:1b}]
+	pathRootMatch                     // {bar:1b}, only as the first node
+)
+
+type pathNode struct {
+	kind    pathNodeKind
+	key     string
+	index   int
+	pattern map[string]interface{}
+}
+
+// Path is a parsed NBT path, using the syntax of Minecraft's /data command, such as
+// Level.Sections[0].BlockStates or Inventory[{Slot:0b}].tag.display.Name.
+//
+// Paths operate on the generic representation Unmarshal produces when decoding into
+// an interface{}, with the root compound as a map[string]interface{}.
+type Path struct {
+	nodes []pathNode
+}
+
+// pathSlot is a location in a tree that a path resolved to.
+type pathSlot struct {
+	get    func() interface{}
+	set    func(value interface{}) error
+	remove func() error
+}
+
+// ParsePath parses an NBT path.
+func ParsePath(s string) (Path, error) {
+	var p Path
+	pos := 0
+
+	if strings.HasPrefix(s, "{") {
+		pattern, next, err := parsePathPattern(s, pos)
+		if err != nil {
+			return Path{}, err
+		}
+		p.nodes = append(p.nodes, pathNode{kind: pathRootMatch, pattern: pattern})
+		pos = next
+	}
+
+	for pos < len(s) {
+		switch c := s[pos]; {
+		case c == '[':
+			node, next, err := parsePathIndex(s, pos)
+			if err != nil {
+				return Path{}, err
+			}
+			p.nodes = append(p.nodes, node)
+			pos = next
+			continue
+		case c == '.' && len(p.nodes) > 0:
+			pos++
+		case len(p.nodes) > 0:
+			return Path{}, fmt.Errorf("path: unexpected '%c' at position %d", c, pos)
+		}
+
+		key, next, err := parsePathKey(s, pos)
+		if err != nil {
+			return Path{}, err
+		}
+		pos = next
+
+		node := pathNode{kind: pathKey, key: key}
+		if pos < len(s) && s[pos] == '{' {
+			node.kind = pathKeyMatch
+			node.pattern, pos, err = parsePathPattern(s, pos)
+			if err != nil {
+				return Path{}, err
+			}
+		}
+		p.nodes = append(p.nodes, node)
+	}
+
+	if len(p.nodes) == 0 {
+		return Path{}, errors.New("path: empty path")
+	}
+	return p, nil
+}
+
+// MustParsePath is like ParsePath but panics if the path cannot be parsed.
+func MustParsePath(s string) Path {
+	p, err := ParsePath(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func parsePathKey(s string, pos int) (string, int, error) {
+	if pos < len(s) && (s[pos] == '"' || s[pos] == '\'') {
+		p := &snbtParser{s: s, pos: pos}
+		key, err := p.quoted()
+		return key, p.pos, err
+	}
+
+	start := pos
+	for pos < len(s) && !strings.ContainsRune(" \"'[]{}.", rune(s[pos])) {
+		pos++
+	}
+	if pos == start {
+		return "", pos, fmt.Errorf("path: expected key at position %d", pos)
+	}
+	return s[start:pos], pos, nil
+}
+
+func parsePathPattern(s string, pos int) (map[string]interface{}, int, error) {
+	p := &snbtParser{s: s, pos: pos}
+	pattern, err := p.compound()
+	if err != nil {
+		return nil, pos, err
+	}
+	return pattern.(map[string]interface{}), p.pos, nil
+}
+
+func parsePathIndex(s string, pos int) (pathNode, int, error) {
+	pos++
+	switch {
+	case pos < len(s) && s[pos] == ']':
+		return pathNode{kind: pathAll}, pos + 1, nil
+	case pos < len(s) && s[pos] == '{':
+		pattern, next, err := parsePathPattern(s, pos)
+		if err != nil {
+			return pathNode{}, pos, err
+		}
+		if next >= len(s) || s[next] != ']' {
+			return pathNode{}, next, fmt.Errorf("path: expected ']' at position %d", next)
+		}
+		return pathNode{kind: pathListMatch, pattern: pattern}, next + 1, nil
+	}
+
+	end := strings.IndexByte(s[pos:], ']')
+	if end < 0 {
+		return pathNode{}, pos, fmt.Errorf("path: expected ']' at position %d", len(s))
+	}
+	index, err := strconv.Atoi(s[pos : pos+end])
+	if err != nil {
+		return pathNode{}, pos, fmt.Errorf("path: invalid index %q at position %d", s[pos:pos+end], pos)
+	}
+	return pathNode{kind: pathIndex, index: index}, pos + end + 1, nil
+}
+
+func (p Path) String() string {
+	sb := strings.Builder{}
+	for i, node := range p.nodes {
+		switch node.kind {
+		case pathKey, pathKeyMatch:
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			if node.key != "" && !strings.ContainsAny(node.key, " \"'[]{}.") {
+				sb.WriteString(node.key)
+			} else {
+				sb.WriteString(quoteSNBT(node.key))
+			}
+			if node.kind == pathKeyMatch {
+				sb.WriteString(FormatSNBT(node.pattern))
+			}
+		case pathIndex:
+			sb.WriteString("[" + strconv.Itoa(node.index) + "]")
+		case pathAll:
+			sb.WriteString("[]")
+		case pathListMatch:
+			sb.WriteString("[" + FormatSNBT(node.pattern) + "]")
+		case pathRootMatch:
+			sb.WriteString(FormatSNBT(node.pattern))
+		}
+	}
+	return sb.String()
+}
+
+// child returns a copy of p addressing the compound entry key.
+func (p Path) child(key string) Path {
+	nodes := append(make([]pathNode, 0, len(p.nodes)+1), p.nodes...)
+	return Path{nodes: append(nodes, pathNode{kind: pathKey, key: key})}
+}
+
+// element returns a copy of p addressing the list element at index.
+func (p Path) element(index int) Path {
+	nodes := append(make([]pathNode, 0, len(p.nodes)+1), p.nodes...)
+	return Path{nodes: append(nodes, pathNode{kind: pathIndex, index: index})}
+}
+
+// all returns a copy of p addressing every element of a list.
+func (p Path) all() Path {
+	nodes := append(make([]pathNode, 0, len(p.nodes)+1), p.nodes...)
+	return Path{nodes: append(nodes, pathNode{kind: pathAll})}
+}
+
+// Get returns every value the path matches in root.
+func (p Path) Get(root interface{}) []interface{} {
+	var values []interface{}
+	for _, slot := range p.resolve(root, nil) {
+		values = append(values, slot.get())
+	}
+	return values
+}
+
+// Count returns the number of values the path matches in root.
+func (p Path) Count(root interface{}) int {
+	return len(p.resolve(root, nil))
+}
+
+// Set replaces every value the path matches in root with a copy of value, creating
+// missing compounds along the way like the /data modify command. It returns the
+// number of values that changed.
+func (p Path) Set(root interface{}, value interface{}) (int, error) {
+	value, err := toTree(value)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, slot := range p.resolve(root, newCompound) {
+		if reflect.DeepEqual(slot.get(), value) {
+			continue
+		}
+		if err := slot.set(copyTree(value)); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// Remove deletes every value the path matches in root and returns how many were
+// removed.
+func (p Path) Remove(root interface{}) (int, error) {
+	slots := p.resolve(root, nil)
+
+	// Remove back to front so earlier list indices stay valid
+	for i := len(slots) - 1; i >= 0; i-- {
+		if err := slots[i].remove(); err != nil {
+			return len(slots) - 1 - i, err
+		}
+	}
+	return len(slots), nil
+}
+
+// GetEncoded is like Get, but reads the values straight from an encoded root tag.
+// Only the parts of data the path leads to are decoded; filters materialize the
+// candidates they test.
+func (p Path) GetEncoded(data []byte) ([]interface{}, error) {
+	d := NewDecoder(bytes.NewReader(data))
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []interface{}
+	err = p.getEncoded(d, tok, p.nodes, &values)
+	return values, err
+}
+
+func (p Path) getEncoded(d *Decoder, tok Token, nodes []pathNode, values *[]interface{}) error {
+	if len(nodes) == 0 {
+		value, err := readTree(d, tok)
+		if err != nil {
+			return err
+		}
+		*values = append(*values, value)
+		return nil
+	}
+
+	switch node := nodes[0]; node.kind {
+	case pathKey:
+		if _, ok := tok.(CompoundStart); !ok {
+			return skipToken(d, tok)
+		}
+		for {
+			child, err := d.Token()
+			if err != nil {
+				return err
+			}
+			if _, ok := child.(End); ok {
+				return nil
+			}
+			if tokenName(child) == node.key {
+				err = p.getEncoded(d, child, nodes[1:], values)
+			} else {
+				err = skipToken(d, child)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case pathIndex, pathAll:
+		list, ok := tok.(ListStart)
+		if !ok {
+			break
+		}
+		index := node.index
+		if index < 0 {
+			index += list.Len
+		}
+		for i := 0; i < list.Len; i++ {
+			child, err := d.Token()
+			if err != nil {
+				return err
+			}
+			if node.kind == pathAll || i == index {
+				err = p.getEncoded(d, child, nodes[1:], values)
+			} else {
+				err = skipToken(d, child)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		_, err := d.Token()
+		return err
+	}
+
+	// Filters and arrays need the whole value at hand
+	value, err := readTree(d, tok)
+	if err != nil {
+		return err
+	}
+	*values = append(*values, Path{nodes: nodes}.Get(value)...)
+	return nil
+}
+
+// resolve returns the slots the path leads to in root. If create is not nil, missing
+// keys are created along the way: with the container the following node expects, or
+// with the result of create for the last node.
+func (p Path) resolve(root interface{}, create func() interface{}) []pathSlot {
+	slots := []pathSlot{{
+		get: func() interface{} { return root },
+		set: func(interface{}) error { return errors.New("path: cannot replace the root tag") },
+		remove: func() error {
+			return errors.New("path: cannot remove the root tag")
+		},
+	}}
+
+	for i, node := range p.nodes {
+		nodeCreate := create
+		if create != nil && i < len(p.nodes)-1 {
+			nodeCreate = p.nodes[i+1].parent
+		}
+
+		var next []pathSlot
+		for _, slot := range slots {
+			next = append(next, node.resolve(slot, nodeCreate)...)
+		}
+		slots = next
+	}
+	return slots
+}
+
+// parent creates the container this node can be resolved against.
+func (n pathNode) parent() interface{} {
+	switch n.kind {
+	case pathIndex, pathAll, pathListMatch:
+		return make([]interface{}, 0)
+	}
+	return newCompound()
+}
+
+func newCompound() interface{} {
+	return make(map[string]interface{})
+}
+
+func (n pathNode) resolve(slot pathSlot, create func() interface{}) []pathSlot {
+	switch n.kind {
+	case pathKey, pathKeyMatch:
+		m, ok := slot.get().(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value, ok := m[n.key]
+		if !ok {
+			if create == nil {
+				return nil
+			}
+			if n.kind == pathKeyMatch {
+				m[n.key] = copyTree(n.pattern)
+			} else {
+				m[n.key] = create()
+			}
+		} else if n.kind == pathKeyMatch && !matches(value, n.pattern) {
+			return nil
+		}
+		return []pathSlot{mapSlot(m, n.key)}
+	case pathIndex:
+		length := listLen(slot.get())
+		index := n.index
+		if index < 0 {
+			index += length
+		}
+		if index < 0 || index >= length {
+			return nil
+		}
+		return []pathSlot{listSlot(slot, index)}
+	case pathAll, pathListMatch:
+		length := listLen(slot.get())
+		var slots []pathSlot
+		for i := 0; i < length; i++ {
+			element := listSlot(slot, i)
+			if n.kind == pathAll || matches(element.get(), n.pattern) {
+				slots = append(slots, element)
+			}
+		}
+		if len(slots) == 0 && create != nil && n.kind == pathListMatch {
+			if list, ok := slot.get().([]interface{}); ok {
+				if err := slot.set(append(list, copyTree(n.pattern))); err == nil {
+					slots = append(slots, listSlot(slot, length))
+				}
+			}
+		}
+		return slots
+	case pathRootMatch:
+		if matches(slot.get(), n.pattern) {
+			return []pathSlot{slot}
+		}
+	}
+	return nil
+}
+
+func mapSlot(m map[string]interface{}, key string) pathSlot {
+	return pathSlot{
+		get: func() interface{} { return m[key] },
+		set: func(value interface{}) error {
+			m[key] = value
+			return nil
+		},
+		remove: func() error {
+			delete(m, key)
+			return nil
+		},
+	}
+}
+
+// listSlot addresses an element of the list or array held by parent. The list is
+// looked up through parent on every access since removing elements replaces it.
+func listSlot(parent pathSlot, index int) pathSlot {
+	return pathSlot{
+		get: func() interface{} {
+			return reflect.ValueOf(parent.get()).Index(index).Interface()
+		},
+		set: func(value interface{}) error {
+			list := reflect.ValueOf(parent.get())
+			element := list.Index(index)
+			if list.Type().Elem().Kind() == reflect.Interface {
+				for i := 0; i < list.Len(); i++ {
+					if i != index && tagTypeOf(list.Index(i).Interface()) != tagTypeOf(value) {
+						return fmt.Errorf("path: cannot insert %v into a list of %v", tagTypeOf(value), tagTypeOf(list.Index(i).Interface()))
+					}
+				}
+			} else if reflect.TypeOf(value) != element.Type() {
+				return fmt.Errorf("path: cannot insert %v into %v", tagTypeOf(value), tagTypeOf(list.Interface()))
+			}
+			element.Set(reflect.ValueOf(value))
+			return nil
+		},
+		remove: func() error {
+			list := reflect.ValueOf(parent.get())
+			removed := reflect.MakeSlice(list.Type(), 0, list.Len()-1)
+			removed = reflect.AppendSlice(removed, list.Slice(0, index))
+			removed = reflect.AppendSlice(removed, list.Slice(index+1, list.Len()))
+			return parent.set(removed.Interface())
+		},
+	}
+}
+
+// listLen returns the number of elements in a list or array value, or zero for
+// anything else.
+func listLen(value interface{}) int {
+	switch tagTypeOf(value) {
+	case TagList, TagByteArray, TagIntArray, TagLongArray:
+		return reflect.ValueOf(value).Len()
+	}
+	return 0
+}
+
+func tokenName(tok Token) string {
+	switch tok := tok.(type) {
+	case CompoundStart:
+		return tok.Name
+	case ListStart:
+		return tok.Name
+	case Value:
+		return tok.Name
+	}
+	return ""
+}
+
+// skipToken discards the rest of the value tok started.
+func skipToken(d *Decoder, tok Token) error {
+	switch tok.(type) {
+	case CompoundStart, ListStart:
+		return d.Skip()
+	}
+	return nil
+}
+
+// readTree materializes the value tok started as a generic tree.
+func readTree(d *Decoder, tok Token) (interface{}, error) {
+	switch tok := tok.(type) {
+	case Value:
+		return tok.Value, nil
+	case ListStart:
+		list := make([]interface{}, tok.Len)
+		for i := range list {
+			child, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			if list[i], err = readTree(d, child); err != nil {
+				return nil, err
+			}
+		}
+		_, err := d.Token()
+		return list, err
+	case CompoundStart:
+		m := make(map[string]interface{})
+		for {
+			child, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := child.(End); ok {
+				return m, nil
+			}
+			if m[tokenName(child)], err = readTree(d, child); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected token %T", tok)
+}
@@ -0,0 +1,151 @@
+package nbt
+
+import (
+	"github.com/junglemc/nbt/test"
+	"reflect"
+	"testing"
+)
+
+func bigTestTree(t *testing.T) map[string]interface{} {
+	var tree interface{}
+	if _, err := Unmarshal(test.BigTestBytes, reflect.ValueOf(&tree).Elem()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	return tree.(map[string]interface{})
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "foo.bar", want: "foo.bar"},
+		{input: `"nested compound test".ham.name`, want: `"nested compound test".ham.name`},
+		{input: "Level.Sections[0].BlockStates", want: "Level.Sections[0].BlockStates"},
+		{input: "Inventory[{Slot:0b}].tag.display.Name", want: "Inventory[{Slot:0b}].tag.display.Name"},
+		{input: "foo[]", want: "foo[]"},
+		{input: "foo[-1][2]", want: "foo[-1][2]"},
+		{input: "{OnGround:1b}.Pos", want: "{OnGround:1b}.Pos"},
+		{input: "Item{id:'minecraft:stone'}.Count", want: `Item{id:"minecraft:stone"}.Count`},
+		{input: "", wantErr: true},
+		{input: "foo[", wantErr: true},
+		{input: "foo[x]", wantErr: true},
+		{input: "foo..bar", wantErr: true},
+		{input: "foo{bar:1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePath(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("got %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPathGet(t *testing.T) {
+	tests := []struct {
+		path string
+		want []interface{}
+	}{
+		{path: "shortTest", want: []interface{}{int16(32767)}},
+		{path: `"nested compound test".egg.name`, want: []interface{}{"Eggbert"}},
+		{path: `"listTest (long)"[1]`, want: []interface{}{int64(12)}},
+		{path: `"listTest (long)"[-1]`, want: []interface{}{int64(15)}},
+		{path: `"listTest (compound)"[].name`, want: []interface{}{"Compound tag #0", "Compound tag #1"}},
+		{path: `"listTest (compound)"[{name:"Compound tag #1"}].created-on`, want: []interface{}{int64(1264099775885)}},
+		{path: `"nested compound test"{ham:{name:"Hampus"}}.egg.value`, want: []interface{}{float32(0.5)}},
+		{path: `{intTest:2147483647}.byteTest`, want: []interface{}{byte(127)}},
+		{path: `"byteArrayTest (the first 1000 values of (n*n*255+n*7)%100, starting with n=0 (0, 62, 34, 16, 8, ...))"[1]`, want: []interface{}{byte(62)}},
+		{path: "missing", want: nil},
+		{path: `"listTest (long)"[5]`, want: nil},
+		{path: `{intTest:0}.byteTest`, want: nil},
+	}
+
+	tree := bigTestTree(t)
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p := MustParsePath(tt.path)
+
+			if got := p.Get(tree); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() got %v, want %v", got, tt.want)
+			}
+			if got := p.Count(tree); got != len(tt.want) {
+				t.Errorf("Count() got %d, want %d", got, len(tt.want))
+			}
+
+			got, err := p.GetEncoded(test.BigTestBytes)
+			if err != nil {
+				t.Fatalf("GetEncoded() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetEncoded() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathSet(t *testing.T) {
+	tree := bigTestTree(t)
+
+	n, err := MustParsePath(`"listTest (compound)"[].name`).Set(tree, "renamed")
+	if err != nil || n != 2 {
+		t.Fatalf("Set() = %d, %v", n, err)
+	}
+	if got := MustParsePath(`"listTest (compound)"[1].name`).Get(tree); !reflect.DeepEqual(got, []interface{}{"renamed"}) {
+		t.Errorf("got %v", got)
+	}
+
+	n, err = MustParsePath("created.deep.value").Set(tree, int32(1))
+	if err != nil || n != 1 {
+		t.Fatalf("Set() = %d, %v", n, err)
+	}
+	if got := MustParsePath("created.deep.value").Get(tree); !reflect.DeepEqual(got, []interface{}{int32(1)}) {
+		t.Errorf("got %v", got)
+	}
+
+	if _, err = MustParsePath(`"listTest (long)"[0]`).Set(tree, "string"); err == nil {
+		t.Errorf("expected an error inserting a string into a list of longs")
+	}
+
+	if _, err = MustParsePath(`{intTest:2147483647}`).Set(tree, int32(1)); err == nil {
+		t.Errorf("expected an error replacing the root")
+	}
+
+	if _, err = MustParsePath("created.deep.value").Set(tree, 5); err == nil {
+		t.Errorf("expected an error setting an int")
+	}
+	if got := MustParsePath("created.deep.value").Get(tree); !reflect.DeepEqual(got, []interface{}{int32(1)}) {
+		t.Errorf("got %v after a failed Set", got)
+	}
+}
+
+func TestPathRemove(t *testing.T) {
+	tree := bigTestTree(t)
+
+	n, err := MustParsePath(`"listTest (long)"[{}]`).Remove(tree)
+	if err != nil || n != 0 {
+		t.Fatalf("Remove() = %d, %v", n, err)
+	}
+
+	n, err = MustParsePath(`"listTest (long)"[]`).Remove(tree)
+	if err != nil || n != 5 {
+		t.Fatalf("Remove() = %d, %v", n, err)
+	}
+	if got := tree["listTest (long)"]; !reflect.DeepEqual(got, []interface{}{}) {
+		t.Errorf("got %v", got)
+	}
+
+	n, err = MustParsePath(`"nested compound test".ham`).Remove(tree)
+	if err != nil || n != 1 {
+		t.Fatalf("Remove() = %d, %v", n, err)
+	}
+	if got := MustParsePath(`"nested compound test".ham`).Count(tree); got != 0 {
+		t.Errorf("got %d", got)
+	}
+}
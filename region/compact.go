@@ -0,0 +1,216 @@
+package region
+
+import (
+	"bufio"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// CompactOptions controls how Compact rewrites a region file.
+type CompactOptions struct {
+	// Compression is the compression type chunks are rewritten with, converting the
+	// region to another compression scheme. Zero keeps the compression of every chunk.
+	// Chunks stored in .mcc files keep theirs unless they shrink enough to fit in the
+	// region, as the files cannot be replaced atomically with it.
+	Compression byte
+
+	// Level is the gzip or zlib compression level, such as zlib.BestCompression. Zero
+	// uses the default level, so zlib.NoCompression cannot be requested; use
+	// CompressionNone to store chunks uncompressed. Chunks are only recompressed if
+	// Compression or Level is set.
+	Level int
+}
+
+// CompactResult reports what Compact did.
+type CompactResult struct {
+	// Chunks is the number of chunks in the region.
+	Chunks int
+
+	// Before and After are the sizes of the region file, not counting .mcc files.
+	Before int64
+	After  int64
+}
+
+// Reclaimed returns the number of bytes freed by compaction.
+func (c CompactResult) Reclaimed() int64 {
+	return c.Before - c.After
+}
+
+// Compact rewrites the region file name with its chunks packed one after the other,
+// dropping the space left free by removed and relocated chunks.
+//
+// Every chunk is decoded with nbt.Unmarshal before anything is replaced, and the file
+// is left untouched if one of them fails. The new region is written to a temporary
+// file which is then renamed over the original.
+func Compact(name string, opts CompactOptions) (CompactResult, error) {
+	r, err := Open(name)
+	if err != nil {
+		return CompactResult{}, err
+	}
+	defer r.Close()
+
+	recompress := opts.Compression != 0 || opts.Level != 0
+	level := opts.Level
+	if level == 0 {
+		level = zlib.DefaultCompression
+	}
+
+	var chunks []packedChunk
+	for i, location := range r.locations {
+		if location == 0 {
+			continue
+		}
+		x, z := i%Width, i/Width
+
+		data, compression, err := r.readCompressed(x, z)
+		if err != nil {
+			return CompactResult{}, err
+		}
+		c := packedChunk{index: i, data: data, compression: compression &^ externalFlag, timestamp: r.timestamps[i]}
+		if compression&externalFlag != 0 && !recompress {
+			c.source = r.externalName(x, z)
+		}
+
+		if recompress {
+			decompressed, err := decompress(c.data, c.compression)
+			if err != nil {
+				return CompactResult{}, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+			}
+			if opts.Compression != 0 {
+				c.compression = opts.Compression
+			}
+			if c.data, err = compressChunk(decompressed, c.compression, level); err != nil {
+				return CompactResult{}, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+			}
+
+			// A .mcc file cannot be replaced together with the region, so a chunk that
+			// is still oversized keeps its file and compression as they are
+			if compression&externalFlag != 0 && sectorsFor(len(c.data)) > maxSectors {
+				c.data, c.compression, c.source = data, compression&^externalFlag, r.externalName(x, z)
+			}
+		}
+		if _, err = decodeChunk(c.data, c.compression); err != nil {
+			return CompactResult{}, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+		}
+		chunks = append(chunks, c)
+	}
+
+	return r.rewrite(chunks)
+}
+
+// packedChunk is a chunk written by rewrite.
+type packedChunk struct {
+	index       int
+	data        []byte
+	compression byte
+	timestamp   uint32
+
+	// source is the .mcc file data was read from and that is kept as is, if any.
+	source string
+}
+
+// rewrite replaces the region file with one holding only the given chunks, packed one
+// after the other. The new region is written to a temporary file which is then
+// renamed over the original.
+func (r *File) rewrite(chunks []packedChunk) (CompactResult, error) {
+	info, err := r.f.Stat()
+	if err != nil {
+		return CompactResult{}, err
+	}
+	result := CompactResult{Before: info.Size()}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.name), "."+filepath.Base(r.name)+".*.tmp")
+	if err != nil {
+		return CompactResult{}, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	header := make([]byte, headerSectors*SectorSize)
+	w := bufio.NewWriter(tmp)
+	w.Write(header)
+
+	offset := headerSectors
+	external := make(map[string][]byte)
+	var keepExternal [chunkCount]bool
+	for _, c := range chunks {
+		data := c.data
+		compression := c.compression
+		if sectorsFor(len(data)) > maxSectors {
+			if externalName := r.externalName(c.index%Width, c.index/Width); externalName != c.source {
+				external[externalName] = data
+			}
+			keepExternal[c.index] = true
+			data = nil
+			compression |= externalFlag
+		}
+
+		sectors := sectorsFor(len(data))
+		buf := make([]byte, sectors*SectorSize)
+		binary.BigEndian.PutUint32(buf, uint32(len(data)+1))
+		buf[4] = compression
+		copy(buf[5:], data)
+		w.Write(buf)
+
+		binary.BigEndian.PutUint32(header[c.index*4:], uint32(offset<<8|sectors))
+		binary.BigEndian.PutUint32(header[SectorSize+c.index*4:], c.timestamp)
+		offset += sectors
+		result.Chunks++
+	}
+
+	if err = w.Flush(); err != nil {
+		return CompactResult{}, err
+	}
+	if _, err = tmp.WriteAt(header, 0); err != nil {
+		return CompactResult{}, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return CompactResult{}, err
+	}
+	if err = tmp.Close(); err != nil {
+		return CompactResult{}, err
+	}
+	if err = os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return CompactResult{}, err
+	}
+
+	// Oversized chunks are written before the new region refers to them, and the .mcc
+	// files of every other slot are removed once it does. The old region must not
+	// refer to a file written here, or a crash before the rename would leave it
+	// pointing at data it cannot read.
+	for externalName, data := range external {
+		if err = writeFileSync(externalName, data); err != nil {
+			return CompactResult{}, err
+		}
+	}
+	if err = os.Rename(tmp.Name(), r.name); err != nil {
+		return CompactResult{}, err
+	}
+	for i, keep := range keepExternal {
+		if keep {
+			continue
+		}
+		if err = os.Remove(r.externalName(i%Width, i/Width)); err != nil && !os.IsNotExist(err) {
+			return CompactResult{}, err
+		}
+	}
+
+	result.After = int64(offset) * SectorSize
+	return result, nil
+}
+
+// decodeChunk decodes compressed chunk data into a generic tree.
+func decodeChunk(data []byte, compression byte) (interface{}, error) {
+	decompressed, err := decompress(data, compression)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	_, err = nbt.Unmarshal(decompressed, reflect.ValueOf(&tree).Elem())
+	return tree, err
+}
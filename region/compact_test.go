@@ -0,0 +1,180 @@
+package region
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fragmentedRegion writes a region with free sectors between its chunks and returns
+// the data of every chunk, by local x coordinate.
+func fragmentedRegion(t *testing.T, name string) map[int][]byte {
+	t.Helper()
+
+	r := openRegion(t, name)
+	chunks := make(map[int][]byte)
+	for x := 0; x < 6; x++ {
+		chunks[x] = chunkData(x * SectorSize)
+		if err := r.WriteChunk(x, 0, chunks[x], CompressionZlib); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, x := range []int{1, 3} {
+		if err := r.RemoveChunk(x, 0); err != nil {
+			t.Fatal(err)
+		}
+		delete(chunks, x)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+func checkChunks(t *testing.T, name string, chunks map[int][]byte, compression byte) {
+	t.Helper()
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if got := len(r.Chunks()); got != len(chunks) {
+		t.Errorf("got %d chunks, want %d", got, len(chunks))
+	}
+	for x, want := range chunks {
+		got, err := r.ReadChunk(x, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("chunk %d,0 differs after compaction", x)
+		}
+		if _, c, _ := r.readCompressed(x, 0); c != compression {
+			t.Errorf("chunk %d,0 has compression %d, want %d", x, c, compression)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	chunks := fragmentedRegion(t, name)
+
+	before, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamps := before.timestamps
+	before.Close()
+
+	result, err := Compact(name, CompactOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Chunks != 4 {
+		t.Errorf("compacted %d chunks, want 4", result.Chunks)
+	}
+	if result.Reclaimed() != 6*SectorSize {
+		t.Errorf("reclaimed %d bytes, want %d", result.Reclaimed(), 6*SectorSize)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != result.After {
+		t.Errorf("file is %d bytes, result reports %d", info.Size(), result.After)
+	}
+
+	checkChunks(t, name, chunks, CompressionZlib)
+
+	after, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer after.Close()
+	if after.timestamps != timestamps {
+		t.Errorf("timestamps were not preserved")
+	}
+	for i := 1; i < len(after.used); i++ {
+		if !after.used[i] {
+			t.Errorf("sector %d is free after compaction", i)
+		}
+	}
+}
+
+func TestCompactRecompress(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	chunks := fragmentedRegion(t, name)
+
+	if _, err := Compact(name, CompactOptions{Compression: CompressionGzip, Level: zlib.BestCompression}); err != nil {
+		t.Fatal(err)
+	}
+	checkChunks(t, name, chunks, CompressionGzip)
+}
+
+func TestCompactCorruptChunk(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	fragmentedRegion(t, name)
+
+	r := openRegion(t, name)
+	if err := r.WriteChunk(7, 0, []byte{0x0a, 0x00}, CompressionNone); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	original, _ := os.ReadFile(name)
+	if _, err := Compact(name, CompactOptions{}); err == nil {
+		t.Errorf("expected an error compacting a region with a corrupt chunk")
+	}
+	if current, _ := os.ReadFile(name); !bytes.Equal(current, original) {
+		t.Errorf("region was modified")
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(name), ".*.tmp")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestCompactRecompressExternal(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "r.0.0.mca")
+	chunks := fragmentedRegion(t, name)
+
+	large := chunkData(maxSectors * SectorSize)
+	r := openRegion(t, name)
+	if err := r.WriteChunk(0, 1, large, CompressionZlib); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	mcc := filepath.Join(dir, "c.0.1.mcc")
+	original, err := os.ReadFile(mcc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Compact(name, CompactOptions{Compression: CompressionGzip}); err != nil {
+		t.Fatal(err)
+	}
+	if current, _ := os.ReadFile(mcc); !bytes.Equal(current, original) {
+		t.Errorf("%s was rewritten", mcc)
+	}
+
+	r, err = Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if got, err := r.ReadChunk(0, 1); err != nil || !bytes.Equal(got, large) {
+		t.Errorf("oversized chunk differs after compaction: %v", err)
+	}
+	if _, c, _ := r.readCompressed(0, 1); c != CompressionZlib|externalFlag {
+		t.Errorf("oversized chunk has compression %d, want %d", c, CompressionZlib|externalFlag)
+	}
+	for x := range chunks {
+		if _, c, _ := r.readCompressed(x, 0); c != CompressionGzip {
+			t.Errorf("chunk %d,0 has compression %d, want %d", x, c, CompressionGzip)
+		}
+	}
+}
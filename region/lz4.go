@@ -0,0 +1,303 @@
+package region
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Chunks compressed with LZ4 hold the stream written by LZ4BlockOutputStream of
+// lz4-java: a sequence of blocks, each with a 21 byte header followed by the block
+// compressed in the LZ4 block format, or stored as is when it does not compress. An
+// empty block ends the stream.
+const (
+	lz4Magic      = "LZ4Block"
+	lz4HeaderSize = len(lz4Magic) + 13
+
+	lz4MethodRaw = 0x10
+	lz4MethodLZ4 = 0x20
+
+	// lz4Level encodes the block size, 1 << (10 + level) bytes. Minecraft uses the
+	// default size of 64 KiB.
+	lz4Level     = 6
+	lz4BlockSize = 1 << (10 + lz4Level)
+
+	// lz4Seed is the xxHash32 seed of the block checksums.
+	lz4Seed = 0x9747b28c
+)
+
+var errLZ4Corrupt = errors.New("lz4: corrupt input")
+
+// lz4Encode compresses data into an LZ4 block stream.
+func lz4Encode(data []byte) []byte {
+	var out []byte
+	for len(data) > 0 {
+		block := data
+		if len(block) > lz4BlockSize {
+			block = block[:lz4BlockSize]
+		}
+		data = data[len(block):]
+
+		method := byte(lz4MethodLZ4)
+		compressed := lz4CompressBlock(block)
+		if len(compressed) >= len(block) {
+			method = lz4MethodRaw
+			compressed = block
+		}
+		out = appendLZ4Header(out, method, len(compressed), len(block), lz4Checksum(block))
+		out = append(out, compressed...)
+	}
+	return appendLZ4Header(out, lz4MethodRaw, 0, 0, 0)
+}
+
+func appendLZ4Header(out []byte, method byte, compressedLen int, originalLen int, checksum uint32) []byte {
+	var header [lz4HeaderSize]byte
+	copy(header[:], lz4Magic)
+	header[8] = method | lz4Level
+	binary.LittleEndian.PutUint32(header[9:], uint32(compressedLen))
+	binary.LittleEndian.PutUint32(header[13:], uint32(originalLen))
+	binary.LittleEndian.PutUint32(header[17:], checksum)
+	return append(out, header[:]...)
+}
+
+// lz4Decode decompresses an LZ4 block stream. The stream ends with an empty block, or
+// at the end of data.
+func lz4Decode(data []byte) ([]byte, error) {
+	var out []byte
+	for len(data) > 0 {
+		if len(data) < lz4HeaderSize || string(data[:len(lz4Magic)]) != lz4Magic {
+			return nil, errors.New("lz4: invalid block header")
+		}
+		method := data[8] & 0xf0
+		blockSize := 1 << (10 + data[8]&0x0f)
+		compressedLen := int(binary.LittleEndian.Uint32(data[9:]))
+		originalLen := int(binary.LittleEndian.Uint32(data[13:]))
+		checksum := binary.LittleEndian.Uint32(data[17:])
+		data = data[lz4HeaderSize:]
+
+		if originalLen == 0 {
+			return out, nil
+		}
+		if originalLen > blockSize || compressedLen > len(data) ||
+			method == lz4MethodRaw && compressedLen != originalLen {
+			return nil, errLZ4Corrupt
+		}
+
+		var block []byte
+		switch method {
+		case lz4MethodRaw:
+			block = data[:compressedLen]
+		case lz4MethodLZ4:
+			var err error
+			if block, err = lz4DecompressBlock(data[:compressedLen], originalLen); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("lz4: unknown block method %#x", method)
+		}
+		if lz4Checksum(block) != checksum {
+			return nil, errors.New("lz4: checksum mismatch")
+		}
+
+		out = append(out, block...)
+		data = data[compressedLen:]
+	}
+	return out, nil
+}
+
+// lz4Checksum is the block checksum of lz4-java, which keeps only the low 28 bits of
+// the hash.
+func lz4Checksum(block []byte) uint32 {
+	return xxh32(block, lz4Seed) & 0x0fffffff
+}
+
+// lz4DecompressBlock decompresses a block in the LZ4 block format into size bytes.
+func lz4DecompressBlock(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	i := 0
+	readLen := func(n int) (int, bool) {
+		for {
+			if i >= len(src) {
+				return 0, false
+			}
+			b := src[i]
+			i++
+			n += int(b)
+			if b != 255 {
+				return n, true
+			}
+		}
+	}
+
+	for i < len(src) {
+		token := src[i]
+		i++
+
+		literals := int(token >> 4)
+		if literals == 15 {
+			var ok bool
+			if literals, ok = readLen(literals); !ok {
+				return nil, errLZ4Corrupt
+			}
+		}
+		if i+literals > len(src) || len(dst)+literals > size {
+			return nil, errLZ4Corrupt
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+		if i == len(src) {
+			break
+		}
+
+		if i+2 > len(src) {
+			return nil, errLZ4Corrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		match := int(token & 0x0f)
+		if match == 15 {
+			var ok bool
+			if match, ok = readLen(match); !ok {
+				return nil, errLZ4Corrupt
+			}
+		}
+		match += 4
+		if offset == 0 || offset > len(dst) || len(dst)+match > size {
+			return nil, errLZ4Corrupt
+		}
+
+		// The match may overlap the bytes it produces, so it is copied byte by byte.
+		start := len(dst) - offset
+		for k := 0; k < match; k++ {
+			dst = append(dst, dst[start+k])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, errLZ4Corrupt
+	}
+	return dst, nil
+}
+
+// lz4CompressBlock compresses src in the LZ4 block format, greedily taking the matches
+// found through a hash table of 4 byte sequences.
+func lz4CompressBlock(src []byte) []byte {
+	const (
+		minMatch     = 4
+		mfLimit      = 12
+		lastLiterals = 5
+		hashLog      = 14
+	)
+
+	var table [1 << hashLog]int32
+	var dst []byte
+	anchor := 0
+	for i := 0; i+mfLimit < len(src); {
+		sequence := binary.LittleEndian.Uint32(src[i:])
+		h := sequence * 2654435761 >> (32 - hashLog)
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if ref < 0 || i-ref > 0xffff || binary.LittleEndian.Uint32(src[ref:]) != sequence {
+			i++
+			continue
+		}
+
+		match := minMatch
+		for i+match < len(src)-lastLiterals && src[ref+match] == src[i+match] {
+			match++
+		}
+		dst = appendLZ4Sequence(dst, src[anchor:i], i-ref, match)
+		i += match
+		anchor = i
+	}
+	return appendLZ4Sequence(dst, src[anchor:], 0, 0)
+}
+
+// appendLZ4Sequence appends literals followed by a match, or only literals for the
+// last sequence of a block, when match is 0.
+func appendLZ4Sequence(dst []byte, literals []byte, offset int, match int) []byte {
+	appendLen := func(n int) {
+		for ; n >= 255; n -= 255 {
+			dst = append(dst, 255)
+		}
+		dst = append(dst, byte(n))
+	}
+
+	token := byte(15 << 4)
+	if len(literals) < 15 {
+		token = byte(len(literals) << 4)
+	}
+	if match > 0 {
+		if match-4 < 15 {
+			token |= byte(match - 4)
+		} else {
+			token |= 15
+		}
+	}
+
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		appendLen(len(literals) - 15)
+	}
+	dst = append(dst, literals...)
+	if match > 0 {
+		dst = append(dst, byte(offset), byte(offset>>8))
+		if match-4 >= 15 {
+			appendLen(match - 4 - 15)
+		}
+	}
+	return dst
+}
+
+const (
+	xxhPrime1 uint32 = 2654435761
+	xxhPrime2 uint32 = 2246822519
+	xxhPrime3 uint32 = 3266489917
+	xxhPrime4 uint32 = 668265263
+	xxhPrime5 uint32 = 374761393
+)
+
+// xxh32 returns the 32-bit xxHash of b.
+func xxh32(b []byte, seed uint32) uint32 {
+	n := len(b)
+	var h uint32
+	if n >= 16 {
+		v1 := seed + xxhPrime1
+		v1 += xxhPrime2
+		v2 := seed + xxhPrime2
+		v3 := seed
+		v4 := seed - xxhPrime1
+		for ; len(b) >= 16; b = b[16:] {
+			v1 = xxhRound(v1, binary.LittleEndian.Uint32(b))
+			v2 = xxhRound(v2, binary.LittleEndian.Uint32(b[4:]))
+			v3 = xxhRound(v3, binary.LittleEndian.Uint32(b[8:]))
+			v4 = xxhRound(v4, binary.LittleEndian.Uint32(b[12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxhPrime5
+	}
+
+	h += uint32(n)
+	for ; len(b) >= 4; b = b[4:] {
+		h += binary.LittleEndian.Uint32(b) * xxhPrime3
+		h = bits.RotateLeft32(h, 17) * xxhPrime4
+	}
+	for _, c := range b {
+		h += uint32(c) * xxhPrime5
+		h = bits.RotateLeft32(h, 11) * xxhPrime1
+	}
+
+	h ^= h >> 15
+	h *= xxhPrime2
+	h ^= h >> 13
+	h *= xxhPrime3
+	h ^= h >> 16
+	return h
+}
+
+func xxhRound(acc uint32, input uint32) uint32 {
+	acc += input * xxhPrime2
+	return bits.RotateLeft32(acc, 13) * xxhPrime1
+}
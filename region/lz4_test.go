@@ -0,0 +1,184 @@
+package region
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestXXH32(t *testing.T) {
+	tests := []struct {
+		input string
+		seed  uint32
+		want  uint32
+	}{
+		{input: "", seed: 0, want: 0x02cc5d05},
+		{input: "abc", seed: 0, want: 0x32d153ff},
+		{input: "Nobody inspects the spammish repetition", seed: 0, want: 0xe2293b2f},
+	}
+
+	for _, tt := range tests {
+		if got := xxh32([]byte(tt.input), tt.seed); got != tt.want {
+			t.Errorf("xxh32(%q, %d) = %#x, want %#x", tt.input, tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestLZ4DecompressBlock(t *testing.T) {
+	// Three literals, a match of six bytes overlapping its output, and a last literal.
+	src := []byte{0x32, 'a', 'b', 'c', 0x03, 0x00, 0x10, 'd'}
+	got, err := lz4DecompressBlock(src, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "abcabcabcd" {
+		t.Errorf("lz4DecompressBlock() = %q, want %q", got, "abcabcabcd")
+	}
+
+	for _, corrupt := range [][]byte{
+		{0x32, 'a', 'b', 'c', 0x09, 0x00},
+		{0x32, 'a', 'b', 'c', 0x00, 0x00},
+		{0xf0},
+		{0x50, 'a'},
+	} {
+		if _, err = lz4DecompressBlock(corrupt, 10); err == nil {
+			t.Errorf("lz4DecompressBlock(%v) succeeded", corrupt)
+		}
+	}
+}
+
+func TestLZ4RoundTrip(t *testing.T) {
+	random := make([]byte, 3*lz4BlockSize+17)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: []byte{}},
+		{name: "short", data: []byte("hello")},
+		{name: "repetitive", data: bytes.Repeat([]byte("Minecraft "), 1000)},
+		{name: "runs", data: bytes.Repeat([]byte{0}, 5000)},
+		{name: "random", data: random},
+		{name: "several blocks", data: []byte(strings.Repeat("palette", 40000))},
+		{name: "chunk", data: chunkData(1000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := lz4Encode(tt.data)
+			decoded, err := lz4Decode(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(decoded, tt.data) {
+				t.Errorf("data differs after round trip")
+			}
+		})
+	}
+}
+
+func TestLZ4Compresses(t *testing.T) {
+	data := bytes.Repeat([]byte("Minecraft "), 1000)
+	if encoded := lz4Encode(data); len(encoded) > len(data)/10 {
+		t.Errorf("encoded %d bytes into %d", len(data), len(encoded))
+	}
+}
+
+func TestLZ4Checksum(t *testing.T) {
+	encoded := lz4Encode([]byte("hello world, hello world"))
+	encoded[lz4HeaderSize+2] ^= 0xff
+	if _, err := lz4Decode(encoded); err == nil {
+		t.Errorf("expected an error decoding a corrupted stream")
+	}
+}
+
+func TestLZ4Chunks(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	chunks := fragmentedRegion(t, name)
+
+	if _, err := Compact(name, CompactOptions{Compression: CompressionLZ4}); err != nil {
+		t.Fatal(err)
+	}
+	checkChunks(t, name, chunks, CompressionLZ4)
+
+	r := openRegion(t, name)
+	if err := r.WriteChunk(9, 0, positionedChunk(9, 0), CompressionLZ4); err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ReadChunk(9, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, positionedChunk(9, 0)) {
+		t.Errorf("chunk differs after writing with LZ4")
+	}
+	r.Close()
+
+	if _, err = Compact(name, CompactOptions{Compression: CompressionZlib}); err != nil {
+		t.Fatal(err)
+	}
+	chunks[9] = positionedChunk(9, 0)
+	checkChunks(t, name, chunks, CompressionZlib)
+}
+
+func TestCustomCompression(t *testing.T) {
+	name := writeRegion(t, t.TempDir(), "r.0.0.mca", []rawChunk{
+		{x: 0, z: 0, compression: CompressionCustom, data: append([]byte{0, 4}, "zstd..."...)},
+	})
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err = r.ReadChunk(0, 0); err == nil || !strings.Contains(err.Error(), `"zstd"`) {
+		t.Errorf("ReadChunk() error = %v, want unsupported custom compression \"zstd\"", err)
+	}
+	problems, err := r.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Kind != UnknownCompression {
+		t.Errorf("Verify() = %v, want an unknown compression", problems)
+	}
+}
+
+// lz4JavaStream is a chunk as LZ4BlockOutputStream of lz4-java writes it with the
+// default 64 KiB blocks: one compressed block, then the empty block ending the stream.
+// The block was compressed by the reference liblz4, which the default compressor of
+// lz4-java wraps, so it is not produced by the encoder under test.
+var lz4JavaStream = []byte{
+	0x4c, 0x5a, 0x34, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x26, 0x7c, 0x00, 0x00, 0x00, 0xd2, 0x00, 0x00,
+	0x00, 0x07, 0x5e, 0x53, 0x03, 0xf6, 0x4e, 0x0a, 0x00, 0x00, 0x03, 0x00, 0x0b, 0x44, 0x61, 0x74,
+	0x61, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x00, 0x00, 0x0d, 0x89, 0x08, 0x00, 0x06, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x00, 0x0e, 0x6d, 0x69, 0x6e, 0x65, 0x63, 0x72, 0x61, 0x66, 0x74,
+	0x3a, 0x66, 0x75, 0x6c, 0x6c, 0x09, 0x00, 0x08, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x0a, 0x00, 0x00, 0x00, 0x03, 0x01, 0x00, 0x01, 0x59, 0x00, 0x0a, 0x00, 0x06, 0x62, 0x69, 0x6f,
+	0x6d, 0x65, 0x73, 0x09, 0x00, 0x07, 0x70, 0x61, 0x6c, 0x65, 0x74, 0x74, 0x65, 0x08, 0x00, 0x00,
+	0x00, 0x01, 0x00, 0x10, 0x3d, 0x00, 0x60, 0x70, 0x6c, 0x61, 0x69, 0x6e, 0x73, 0x15, 0x00, 0x3f,
+	0x01, 0x59, 0x01, 0x31, 0x00, 0x1d, 0x1f, 0x02, 0x31, 0x00, 0x15, 0x50, 0x6e, 0x73, 0x00, 0x00,
+	0x00, 0x4c, 0x5a, 0x34, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x16, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+}
+
+func TestLZ4DecodeReference(t *testing.T) {
+	got, err := lz4Decode(lz4JavaStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree, err := nbt.ParseSNBT(`{DataVersion:3465,Status:"minecraft:full",sections:[` +
+		`{Y:0b,biomes:{palette:["minecraft:plains"]}},` +
+		`{Y:1b,biomes:{palette:["minecraft:plains"]}},` +
+		`{Y:2b,biomes:{palette:["minecraft:plains"]}}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := nbt.Marshal("", tree); !bytes.Equal(got, want) {
+		t.Errorf("got:\n[% 2x]\nwant:\n[% 2x]", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+// Package region reads and writes the Anvil region files (.mca) that hold the chunks of a
+// Minecraft world.
+//
+// A region file holds 32x32 chunks. It starts with an 8 KiB header: a table of 1024
+// chunk locations followed by a table of 1024 timestamps. The rest of the file is
+// divided into 4 KiB sectors, and every present chunk occupies a run of sectors
+// holding its length, its compression type and its compressed NBT data. Chunks that
+// do not fit in 255 sectors are stored next to the region file, in c.X.Z.mcc.
+package region
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+const (
+	// SectorSize is the size of the sectors a region file is divided into.
+	SectorSize = 4096
+
+	// Width is the number of chunks along each side of a region.
+	Width = 32
+
+	headerSectors = 2
+	chunkCount    = Width * Width
+)
+
+// Compression types of a chunk.
+const (
+	CompressionGzip = 1
+	CompressionZlib = 2
+	CompressionNone = 3
+	CompressionLZ4  = 4
+
+	// CompressionCustom is followed by the name of the algorithm, which is not
+	// supported.
+	CompressionCustom = 127
+
+	// externalFlag is set in the compression type of chunks stored in a .mcc file.
+	externalFlag = 0x80
+)
+
+// ErrNotPresent is returned when reading a chunk that has not been generated.
+var ErrNotPresent = errors.New("region: chunk not present")
+
+// Chunk describes a chunk present in a region file.
+type Chunk struct {
+	// X and Z are the coordinates of the chunk in the world.
+	X, Z int
+
+	// Timestamp is the time the chunk was last saved.
+	Timestamp time.Time
+}
+
+// File is an open region file.
+type File struct {
+	f    *os.File
+	name string
+
+	// x and z are the coordinates of the region, parsed from the file name.
+	x, z int
+
+	locations  [chunkCount]uint32
+	timestamps [chunkCount]uint32
+
+	// used marks the sectors taken by the header and by chunks.
+	used []bool
+}
+
+// Open opens a region file for reading. The region coordinates are taken from the
+// file name, r.X.Z.mca, and default to 0 when the name does not follow the pattern.
+func Open(name string) (*File, error) {
+	return OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens a region file with the given flags, like os.OpenFile. Chunks can be
+// written if the file is opened with os.O_RDWR, and os.O_CREATE creates an empty
+// region.
+func OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &File{f: f, name: name}
+	if err = r.readHeader(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("region: %s: %w", name, err)
+	}
+	return r, nil
+}
+
+func (r *File) readHeader() error {
+	fmt.Sscanf(filepath.Base(r.name), "r.%d.%d.mca", &r.x, &r.z)
+
+	info, err := r.f.Stat()
+	if err != nil {
+		return err
+	}
+	// An empty file is a region without chunks.
+	r.used = []bool{true, true}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	header := make([]byte, headerSectors*SectorSize)
+	if _, err = r.f.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("cannot read header: %w", err)
+	}
+	for i := 0; i < chunkCount; i++ {
+		r.locations[i] = binary.BigEndian.Uint32(header[i*4:])
+		r.timestamps[i] = binary.BigEndian.Uint32(header[SectorSize+i*4:])
+		if r.locations[i] != 0 {
+			r.markSectors(r.locations[i], true)
+		}
+	}
+	return nil
+}
+
+// Close closes the region file.
+func (r *File) Close() error {
+	return r.f.Close()
+}
+
+// index returns the position of a chunk in the header tables. Chunks are addressed by
+// their world coordinates, of which only the position within the region matters.
+func index(x, z int) int {
+	return (x & (Width - 1)) + (z&(Width-1))*Width
+}
+
+// Chunks returns every chunk present in the region, in the order of the header.
+func (r *File) Chunks() []Chunk {
+	var chunks []Chunk
+	for i, location := range r.locations {
+		if location == 0 {
+			continue
+		}
+		chunks = append(chunks, Chunk{
+			X:         r.x*Width + i%Width,
+			Z:         r.z*Width + i/Width,
+			Timestamp: time.Unix(int64(r.timestamps[i]), 0),
+		})
+	}
+	return chunks
+}
+
+// HasChunk reports whether the chunk is present in the region.
+func (r *File) HasChunk(x, z int) bool {
+	return r.locations[index(x, z)] != 0
+}
+
+// Timestamp returns the time the chunk was last saved.
+func (r *File) Timestamp(x, z int) time.Time {
+	return time.Unix(int64(r.timestamps[index(x, z)]), 0)
+}
+
+// ReadChunk returns the uncompressed NBT data of a chunk.
+func (r *File) ReadChunk(x, z int) ([]byte, error) {
+	data, compression, err := r.readCompressed(x, z)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed, err := decompress(data, compression&^externalFlag)
+	if err != nil {
+		return nil, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+	}
+	return decompressed, nil
+}
+
+// readCompressed returns the data of a chunk as stored, in the region file or in its
+// .mcc file, along with its compression type including the external flag.
+func (r *File) readCompressed(x, z int) ([]byte, byte, error) {
+	location := r.locations[index(x, z)]
+	if location == 0 {
+		return nil, 0, ErrNotPresent
+	}
+	offset := int64(location>>8) * SectorSize
+	sectors := int(location & 0xff)
+	if offset < headerSectors*SectorSize {
+		return nil, 0, fmt.Errorf("region: chunk %d,%d overlaps the header", x, z)
+	}
+
+	var header [5]byte
+	if _, err := r.f.ReadAt(header[:], offset); err != nil {
+		return nil, 0, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+	}
+	length := int(binary.BigEndian.Uint32(header[:4]))
+	compression := header[4]
+
+	if compression&externalFlag != 0 {
+		data, err := os.ReadFile(r.externalName(x, z))
+		if err != nil {
+			return nil, 0, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+		}
+		return data, compression, nil
+	}
+
+	if length < 1 || length+4 > sectors*SectorSize {
+		return nil, 0, fmt.Errorf("region: chunk %d,%d has invalid length %d", x, z, length)
+	}
+	data := make([]byte, length-1)
+	if _, err := r.f.ReadAt(data, offset+5); err != nil {
+		return nil, 0, fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+	}
+	return data, compression, nil
+}
+
+// Decoder returns a Decoder reading the NBT data of a chunk.
+func (r *File) Decoder(x, z int) (*nbt.Decoder, error) {
+	data, err := r.ReadChunk(x, z)
+	if err != nil {
+		return nil, err
+	}
+	return nbt.NewDecoder(bytes.NewReader(data)), nil
+}
+
+// Unmarshal decodes the NBT data of a chunk into value, like nbt.Unmarshal.
+func (r *File) Unmarshal(x, z int, value reflect.Value) error {
+	data, err := r.ReadChunk(x, z)
+	if err != nil {
+		return err
+	}
+	_, err = nbt.Unmarshal(data, value)
+	return err
+}
+
+// externalName returns the name of the file an oversized chunk is stored in.
+func (r *File) externalName(x, z int) string {
+	x = r.x*Width + x&(Width-1)
+	z = r.z*Width + z&(Width-1)
+	return filepath.Join(filepath.Dir(r.name), fmt.Sprintf("c.%d.%d.mcc", x, z))
+}
+
+func decompress(data []byte, compression byte) ([]byte, error) {
+	var zr io.Reader
+	var err error
+	switch compression {
+	case CompressionGzip:
+		zr, err = gzip.NewReader(bytes.NewReader(data))
+	case CompressionZlib:
+		zr, err = zlib.NewReader(bytes.NewReader(data))
+	case CompressionNone:
+		return data, nil
+	case CompressionLZ4:
+		return lz4Decode(data)
+	case CompressionCustom:
+		return nil, fmt.Errorf("unsupported custom compression %q", customCompressionName(data))
+	default:
+		return nil, fmt.Errorf("unsupported compression type %d", compression)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// customCompressionName returns the name of the algorithm of a chunk using custom
+// compression, stored before its data as a length-prefixed string.
+func customCompressionName(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	length := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+length {
+		return ""
+	}
+	return string(data[2 : 2+length])
+}
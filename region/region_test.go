@@ -0,0 +1,196 @@
+package region
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/test"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rawChunk struct {
+	x, z        int
+	compression byte
+	data        []byte
+	timestamp   uint32
+	external    bool
+}
+
+func compress(t *testing.T, data []byte, compression byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	switch compression {
+	case CompressionGzip:
+		w := gzip.NewWriter(buf)
+		w.Write(data)
+		w.Close()
+	case CompressionZlib:
+		w := zlib.NewWriter(buf)
+		w.Write(data)
+		w.Close()
+	default:
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// writeRegion lays out a region file by hand, one chunk after the other.
+func writeRegion(t *testing.T, dir string, name string, chunks []rawChunk) string {
+	t.Helper()
+
+	file := make([]byte, headerSectors*SectorSize)
+	for _, c := range chunks {
+		data := compress(t, c.data, c.compression)
+		compression := c.compression
+		if c.external {
+			mcc := filepath.Join(dir, fmt.Sprintf("c.%d.%d.mcc", c.x, c.z))
+			if err := os.WriteFile(mcc, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			data = nil
+			compression |= externalFlag
+		}
+
+		payload := make([]byte, 5, 5+len(data))
+		binary.BigEndian.PutUint32(payload, uint32(len(data)+1))
+		payload[4] = compression
+		payload = append(payload, data...)
+
+		sectors := (len(payload) + SectorSize - 1) / SectorSize
+		offset := len(file) / SectorSize
+		i := index(c.x, c.z)
+		binary.BigEndian.PutUint32(file[i*4:], uint32(offset<<8|sectors))
+		binary.BigEndian.PutUint32(file[SectorSize+i*4:], c.timestamp)
+		file = append(file, payload...)
+		file = append(file, make([]byte, sectors*SectorSize-len(payload))...)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	name := writeRegion(t, dir, "r.-1.2.mca", []rawChunk{
+		{x: -32, z: 64, compression: CompressionGzip, data: test.BigTestBytes, timestamp: 1000},
+		{x: -31, z: 64, compression: CompressionZlib, data: test.BigTestBytes, timestamp: 2000},
+		{x: -1, z: 95, compression: CompressionNone, data: test.BananramaBytes, timestamp: 3000},
+		{x: -2, z: 95, compression: CompressionZlib, data: test.BigTestBytes, timestamp: 4000, external: true},
+	})
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	wantChunks := []Chunk{
+		{X: -32, Z: 64, Timestamp: time.Unix(1000, 0)},
+		{X: -31, Z: 64, Timestamp: time.Unix(2000, 0)},
+		{X: -2, Z: 95, Timestamp: time.Unix(4000, 0)},
+		{X: -1, Z: 95, Timestamp: time.Unix(3000, 0)},
+	}
+	if got := r.Chunks(); !reflect.DeepEqual(got, wantChunks) {
+		t.Errorf("Chunks() = %v, want %v", got, wantChunks)
+	}
+
+	tests := []struct {
+		name string
+		x, z int
+		want []byte
+	}{
+		{name: "gzip", x: -32, z: 64, want: test.BigTestBytes},
+		{name: "zlib", x: -31, z: 64, want: test.BigTestBytes},
+		{name: "uncompressed", x: -1, z: 95, want: test.BananramaBytes},
+		{name: "external", x: -2, z: 95, want: test.BigTestBytes},
+		{name: "local coordinates", x: 31, z: 31, want: test.BananramaBytes},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.ReadChunk(tt.x, tt.z)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("ReadChunk(%d, %d) returned different data", tt.x, tt.z)
+			}
+		})
+	}
+
+	if r.HasChunk(5, 5) {
+		t.Errorf("HasChunk(5, 5) = true")
+	}
+	if _, err = r.ReadChunk(5, 5); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("ReadChunk(5, 5) error = %v, want ErrNotPresent", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	name := writeRegion(t, t.TempDir(), "r.0.0.mca", []rawChunk{
+		{x: 3, z: 4, compression: CompressionZlib, data: test.BigTestBytes},
+	})
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var got test.BigTestPartial
+	if err = r.Unmarshal(3, 4, reflect.ValueOf(&got).Elem()); err != nil {
+		t.Fatal(err)
+	}
+	if got.IntTest != 2147483647 {
+		t.Errorf("IntTest = %d, want 2147483647", got.IntTest)
+	}
+
+	d, err := r.Decoder(3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := d.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok != (nbt.CompoundStart{Name: "Level"}) {
+		t.Errorf("first token = %#v, want the Level compound", tok)
+	}
+}
+
+func TestOpenEmpty(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	if err := os.WriteFile(name, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if chunks := r.Chunks(); len(chunks) != 0 {
+		t.Errorf("Chunks() = %v, want none", chunks)
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	if err := os.WriteFile(name, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(name); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,345 @@
+package region
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ProblemKind is the kind of damage found by Verify.
+type ProblemKind int
+
+const (
+	// Overlap is a chunk sharing sectors with another chunk or with the header.
+	Overlap ProblemKind = iota
+	// PastEOF is a chunk whose sectors extend past the end of the file.
+	PastEOF
+	// BadLength is a chunk whose length does not fit in its sectors.
+	BadLength
+	// UnknownCompression is a chunk with a compression type that is not supported.
+	UnknownCompression
+	// DecodeFailed is a chunk whose data cannot be decompressed or decoded.
+	DecodeFailed
+	// WrongPosition is a chunk whose xPos and zPos do not match its slot.
+	WrongPosition
+)
+
+func (k ProblemKind) String() string {
+	switch k {
+	case Overlap:
+		return "overlapping sectors"
+	case PastEOF:
+		return "past end of file"
+	case BadLength:
+		return "bad length"
+	case UnknownCompression:
+		return "unknown compression"
+	case DecodeFailed:
+		return "decode failed"
+	case WrongPosition:
+		return "wrong position"
+	}
+	return fmt.Sprintf("ProblemKind(%d)", int(k))
+}
+
+// Action is what Repair did with a damaged chunk.
+type Action int
+
+const (
+	// NotRepaired is the action of problems reported by Verify.
+	NotRepaired Action = iota
+	// Kept chunks are readable and were copied to sectors of their own.
+	Kept
+	// Dropped chunks were removed from the region.
+	Dropped
+	// Relocated chunks were moved to the slot matching their xPos and zPos.
+	Relocated
+)
+
+func (a Action) String() string {
+	switch a {
+	case NotRepaired:
+		return "not repaired"
+	case Kept:
+		return "kept"
+	case Dropped:
+		return "dropped"
+	case Relocated:
+		return "relocated"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// Problem is damage found in one chunk of a region.
+type Problem struct {
+	// X and Z are the world coordinates of the slot holding the chunk.
+	X, Z int
+
+	Kind   ProblemKind
+	Detail string
+
+	// Action is set by Repair. ToX and ToZ hold the new coordinates of relocated chunks.
+	Action   Action
+	ToX, ToZ int
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("chunk %d,%d: %v: %s", p.X, p.Z, p.Kind, p.Detail)
+	switch p.Action {
+	case NotRepaired:
+		return s
+	case Relocated:
+		return fmt.Sprintf("%s (relocated to %d,%d)", s, p.ToX, p.ToZ)
+	}
+	return fmt.Sprintf("%s (%v)", s, p.Action)
+}
+
+// Report is the outcome of Repair.
+type Report struct {
+	Problems []Problem
+
+	// Dropped and Relocated count the chunks removed and moved.
+	Dropped   int
+	Relocated int
+
+	CompactResult
+}
+
+// WriteTo writes the report as text, one problem per line followed by a summary.
+func (r Report) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, p := range r.Problems {
+		n, err := fmt.Fprintln(w, p)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err := fmt.Fprintf(w, "%d problems, %d chunks dropped, %d relocated, %d chunks left, %d bytes reclaimed\n",
+		len(r.Problems), r.Dropped, r.Relocated, r.Chunks, r.Reclaimed())
+	return written + int64(n), err
+}
+
+// chunkCheck is the outcome of checking one slot.
+type chunkCheck struct {
+	problems []Problem
+
+	// data and compression hold the stored data of a readable chunk, and source its
+	// .mcc file if it is oversized.
+	data        []byte
+	compression byte
+	source      string
+
+	// readable is set when the chunk decodes, and hasPosition when it records the
+	// coordinates in xPos and zPos.
+	readable    bool
+	hasPosition bool
+	x, z        int
+}
+
+// Verify checks every chunk of the region and returns the problems found: chunks
+// sharing sectors, extending past the end of the file or with a bad length, chunks
+// with an unknown compression type or that fail to decode, and chunks whose xPos and
+// zPos do not match their slot.
+func (r *File) Verify() ([]Problem, error) {
+	checks, err := r.check()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, c := range checks {
+		if c != nil {
+			problems = append(problems, c.problems...)
+		}
+	}
+	return problems, nil
+}
+
+func (r *File) check() ([]*chunkCheck, error) {
+	info, err := r.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileSectors := int((info.Size() + SectorSize - 1) / SectorSize)
+
+	checks := make([]*chunkCheck, chunkCount)
+	owners := make(map[int]int)
+	for i, location := range r.locations {
+		if location == 0 {
+			continue
+		}
+		c := &chunkCheck{}
+		checks[i] = c
+		x, z := r.x*Width+i%Width, r.z*Width+i/Width
+		report := func(kind ProblemKind, format string, args ...interface{}) {
+			c.problems = append(c.problems, Problem{X: x, Z: z, Kind: kind, Detail: fmt.Sprintf(format, args...)})
+		}
+
+		offset := int(location >> 8)
+		sectors := int(location & 0xff)
+		if offset < headerSectors {
+			report(Overlap, "sector %d is part of the header", offset)
+			continue
+		}
+		if offset+sectors > fileSectors {
+			report(PastEOF, "sectors %d to %d, file has %d", offset, offset+sectors-1, fileSectors)
+			continue
+		}
+		for sector := offset; sector < offset+sectors; sector++ {
+			owner, ok := owners[sector]
+			if !ok {
+				owners[sector] = i
+				continue
+			}
+			if len(checks[owner].problems) == 0 || checks[owner].problems[len(checks[owner].problems)-1].Kind != Overlap {
+				checks[owner].problems = append(checks[owner].problems, Problem{
+					X: r.x*Width + owner%Width, Z: r.z*Width + owner/Width, Kind: Overlap,
+					Detail: fmt.Sprintf("shares sector %d with chunk %d,%d", sector, x, z),
+				})
+			}
+			report(Overlap, "shares sector %d with chunk %d,%d", sector, r.x*Width+owner%Width, r.z*Width+owner/Width)
+			break
+		}
+
+		var header [5]byte
+		if _, err = r.f.ReadAt(header[:], int64(offset)*SectorSize); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint32(header[:4]))
+		if length < 1 || length+4 > sectors*SectorSize {
+			report(BadLength, "length %d does not fit in %d sectors", length, sectors)
+			continue
+		}
+		compression := header[4] &^ externalFlag
+		switch compression {
+		case CompressionGzip, CompressionZlib, CompressionNone, CompressionLZ4:
+		case CompressionCustom:
+			data, _, _ := r.readCompressed(i%Width, i/Width)
+			report(UnknownCompression, "custom compression %q", customCompressionName(data))
+			continue
+		default:
+			report(UnknownCompression, "compression type %d", header[4])
+			continue
+		}
+
+		data, _, err := r.readCompressed(i%Width, i/Width)
+		if err != nil {
+			report(DecodeFailed, "%v", err)
+			continue
+		}
+		tree, err := decodeChunk(data, compression)
+		if err != nil {
+			report(DecodeFailed, "%v", err)
+			continue
+		}
+		c.data = data
+		c.compression = compression
+		if header[4]&externalFlag != 0 {
+			c.source = r.externalName(i%Width, i/Width)
+		}
+		c.readable = true
+
+		c.x, c.z, c.hasPosition = chunkPosition(tree)
+		if c.hasPosition && (c.x != x || c.z != z) {
+			report(WrongPosition, "xPos %d, zPos %d", c.x, c.z)
+		}
+	}
+	return checks, nil
+}
+
+// chunkPosition returns xPos and zPos, found at the root of the chunk since 1.18 and
+// in its Level compound before.
+func chunkPosition(tree interface{}) (x int, z int, ok bool) {
+	root, _ := tree.(map[string]interface{})
+	if level, isCompound := root["Level"].(map[string]interface{}); isCompound {
+		root = level
+	}
+	xPos, xOk := root["xPos"].(int32)
+	zPos, zOk := root["zPos"].(int32)
+	return int(xPos), int(zPos), xOk && zOk
+}
+
+// Repair rewrites the region file name without its damaged chunks. Readable chunks are
+// kept, moved to sectors of their own if they overlapped others. Chunks whose xPos and
+// zPos point to another slot of the region are relocated there, unless that slot holds
+// a valid chunk. Every other damaged chunk is dropped.
+func Repair(name string) (Report, error) {
+	r, err := Open(name)
+	if err != nil {
+		return Report{}, err
+	}
+	defer r.Close()
+
+	checks, err := r.check()
+	if err != nil {
+		return Report{}, err
+	}
+
+	// valid reports whether a slot holds a chunk that stays where it is.
+	valid := func(c *chunkCheck) bool {
+		if c == nil || !c.readable {
+			return false
+		}
+		for _, p := range c.problems {
+			if p.Kind == WrongPosition {
+				return false
+			}
+		}
+		return true
+	}
+
+	report := Report{}
+	var taken [chunkCount]bool
+	var chunks []packedChunk
+	for i, c := range checks {
+		if valid(c) {
+			taken[i] = true
+			chunks = append(chunks, packedChunk{index: i, data: c.data, compression: c.compression, timestamp: r.timestamps[i], source: c.source})
+		}
+	}
+
+	for i, c := range checks {
+		if c == nil || len(c.problems) == 0 {
+			continue
+		}
+
+		action := Dropped
+		target := i
+		if valid(c) {
+			action = Kept
+		} else if c.readable && c.hasPosition && c.x>>5 == r.x && c.z>>5 == r.z && !taken[index(c.x, c.z)] {
+			action = Relocated
+			target = index(c.x, c.z)
+			taken[target] = true
+			chunks = append(chunks, packedChunk{index: target, data: c.data, compression: c.compression, timestamp: r.timestamps[i]})
+		}
+
+		for _, p := range c.problems {
+			p.Action = action
+			if action == Relocated {
+				p.ToX, p.ToZ = c.x, c.z
+			}
+			report.Problems = append(report.Problems, p)
+		}
+		switch action {
+		case Dropped:
+			report.Dropped++
+		case Relocated:
+			report.Relocated++
+		}
+	}
+
+	if len(report.Problems) == 0 {
+		info, err := r.f.Stat()
+		if err != nil {
+			return Report{}, err
+		}
+		report.CompactResult = CompactResult{Chunks: len(chunks), Before: info.Size(), After: info.Size()}
+		return report, nil
+	}
+	sort.Slice(chunks, func(a, b int) bool { return chunks[a].index < chunks[b].index })
+	report.CompactResult, err = r.rewrite(chunks)
+	return report, err
+}
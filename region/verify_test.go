@@ -0,0 +1,170 @@
+package region
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func positionedChunk(x, z int) []byte {
+	return nbt.Marshal("", map[string]interface{}{"xPos": int32(x), "zPos": int32(z), "Status": "minecraft:full"})
+}
+
+// damagedRegion writes a region with one chunk for every kind of problem.
+func damagedRegion(t *testing.T) string {
+	t.Helper()
+
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	r := openRegion(t, name)
+	write := func(x int, data []byte) {
+		t.Helper()
+		if err := r.WriteChunk(x, 0, data, CompressionZlib); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corrupt := func(x int, at int64, data []byte) {
+		t.Helper()
+		offset := int64(r.locations[index(x, 0)]>>8) * SectorSize
+		if _, err := r.f.WriteAt(data, offset+at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(0, positionedChunk(0, 0))
+	write(1, nbt.Marshal("", map[string]interface{}{"Level": map[string]interface{}{"xPos": int32(1), "zPos": int32(0)}}))
+
+	// Chunk 2 points at the sectors of chunk 0.
+	if err := r.writeHeader(index(2, 0), r.locations[index(0, 0)], 0); err != nil {
+		t.Fatal(err)
+	}
+	// Chunk 3 points past the end of the file.
+	if err := r.writeHeader(index(3, 0), 1000<<8|1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	write(4, positionedChunk(4, 0))
+	corrupt(4, 0, []byte{0x7f, 0, 0, 0})
+	write(5, positionedChunk(5, 0))
+	corrupt(5, 4, []byte{9})
+	write(6, positionedChunk(6, 0))
+	corrupt(6, 8, []byte{0xde, 0xad, 0xbe, 0xef})
+
+	// Chunk 7 belongs in the empty slot 8, chunk 9 in the occupied slot 0 and chunk 10
+	// in another region.
+	write(7, positionedChunk(8, 0))
+	write(9, positionedChunk(0, 0))
+	write(10, positionedChunk(42, 0))
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestVerify(t *testing.T) {
+	r, err := Open(damagedRegion(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	problems, err := r.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[int][]ProblemKind)
+	for _, p := range problems {
+		if p.Z != 0 {
+			t.Errorf("problem reported at %d,%d", p.X, p.Z)
+		}
+		got[p.X] = append(got[p.X], p.Kind)
+	}
+	want := map[int][]ProblemKind{
+		0:  {Overlap},
+		2:  {Overlap, WrongPosition},
+		3:  {PastEOF},
+		4:  {BadLength},
+		5:  {UnknownCompression},
+		6:  {DecodeFailed},
+		7:  {WrongPosition},
+		9:  {WrongPosition},
+		10: {WrongPosition},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Verify() = %v, want %v", got, want)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	name := damagedRegion(t)
+	report, err := Repair(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := make(map[int]Action)
+	for _, p := range report.Problems {
+		actions[p.X] = p.Action
+	}
+	wantActions := map[int]Action{0: Kept, 2: Dropped, 3: Dropped, 4: Dropped, 5: Dropped, 6: Dropped, 7: Relocated, 9: Dropped, 10: Dropped}
+	if !reflect.DeepEqual(actions, wantActions) {
+		t.Errorf("actions = %v, want %v", actions, wantActions)
+	}
+	if report.Dropped != 7 || report.Relocated != 1 || report.Chunks != 3 {
+		t.Errorf("report counts %d dropped, %d relocated, %d chunks, want 7, 1 and 3", report.Dropped, report.Relocated, report.Chunks)
+	}
+
+	buf := &bytes.Buffer{}
+	if _, err = report.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"chunk 7,0: wrong position: xPos 8, zPos 0 (relocated to 8,0)\n",
+		"chunk 3,0: past end of file: sectors 1000 to 1000, file has ",
+		"10 problems, 7 chunks dropped, 1 relocated, 3 chunks left, ",
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("report does not contain %q:\n%s", line, buf.String())
+		}
+	}
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var present []int
+	for _, c := range r.Chunks() {
+		present = append(present, c.X)
+	}
+	sort.Ints(present)
+	if !reflect.DeepEqual(present, []int{0, 1, 8}) {
+		t.Errorf("chunks left at %v, want 0, 1 and 8", present)
+	}
+	if problems, err := r.Verify(); err != nil || len(problems) != 0 {
+		t.Errorf("Verify() after repair = %v, %v", problems, err)
+	}
+}
+
+func TestRepairHealthy(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	r := openRegion(t, name)
+	if err := r.WriteChunk(0, 0, positionedChunk(0, 0), CompressionZlib); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	report, err := Repair(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 0 || report.Chunks != 1 || report.Reclaimed() != 0 {
+		t.Errorf("Repair() = %+v, want no problems", report)
+	}
+}
@@ -0,0 +1,217 @@
+package region
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// maxSectors is the largest number of sectors a chunk can occupy in the region file.
+// Larger chunks are stored in a separate .mcc file.
+const maxSectors = 255
+
+// WriteChunk compresses the NBT data of a chunk and stores it in the region, setting
+// its timestamp to the current time.
+//
+// The data is written to free sectors, reusing the space of removed and relocated
+// chunks, and only then is the header pointed at it. A crash while writing leaves the
+// previous version of the chunk in place.
+func (r *File) WriteChunk(x, z int, data []byte, compression byte) error {
+	compressed, err := compressChunk(data, compression, zlib.DefaultCompression)
+	if err != nil {
+		return fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+	}
+
+	external := sectorsFor(len(compressed)) > maxSectors
+	if external {
+		if err = writeFileSync(r.externalName(x, z), compressed); err != nil {
+			return fmt.Errorf("region: chunk %d,%d: %w", x, z, err)
+		}
+		compressed = nil
+		compression |= externalFlag
+	}
+
+	sectors := sectorsFor(len(compressed))
+	buf := make([]byte, sectors*SectorSize)
+	binary.BigEndian.PutUint32(buf, uint32(len(compressed)+1))
+	buf[4] = compression
+	copy(buf[5:], compressed)
+
+	offset := r.allocate(sectors)
+	if offset >= 1<<24 {
+		r.free(offset, sectors)
+		return fmt.Errorf("region: chunk %d,%d: region file is full", x, z)
+	}
+	if _, err = r.f.WriteAt(buf, int64(offset)*SectorSize); err != nil {
+		r.free(offset, sectors)
+		return err
+	}
+	if err = r.f.Sync(); err != nil {
+		r.free(offset, sectors)
+		return err
+	}
+
+	i := index(x, z)
+	previous := r.locations[i]
+	if err = r.writeHeader(i, uint32(offset<<8|sectors), uint32(time.Now().Unix())); err != nil {
+		r.free(offset, sectors)
+		return err
+	}
+	if previous != 0 {
+		r.markSectors(previous, false)
+	}
+	if !external {
+		if err = os.Remove(r.externalName(x, z)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Marshal encodes value with nbt.Marshal as an unnamed root tag and stores it as the
+// chunk, compressed with zlib like vanilla does.
+func (r *File) Marshal(x, z int, value interface{}) error {
+	return r.WriteChunk(x, z, nbt.Marshal("", value), CompressionZlib)
+}
+
+// RemoveChunk removes a chunk from the region. Its sectors are reused by later writes.
+func (r *File) RemoveChunk(x, z int) error {
+	i := index(x, z)
+	previous := r.locations[i]
+	if previous == 0 {
+		return nil
+	}
+	if err := r.writeHeader(i, 0, 0); err != nil {
+		return err
+	}
+	r.markSectors(previous, false)
+
+	if err := os.Remove(r.externalName(x, z)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeHeader updates the location and timestamp of a chunk in the file and in memory.
+func (r *File) writeHeader(i int, location uint32, timestamp uint32) error {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], location)
+	if _, err := r.f.WriteAt(b[:], int64(i*4)); err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(b[:], timestamp)
+	if _, err := r.f.WriteAt(b[:], int64(SectorSize+i*4)); err != nil {
+		return err
+	}
+	if err := r.f.Sync(); err != nil {
+		return err
+	}
+
+	r.locations[i] = location
+	r.timestamps[i] = timestamp
+	return nil
+}
+
+func sectorsFor(length int) int {
+	return (length + 5 + SectorSize - 1) / SectorSize
+}
+
+// allocate marks the first run of free sectors long enough, or sectors past the end
+// of the file, as used and returns the offset of the first one.
+func (r *File) allocate(sectors int) int {
+	start := 0
+	for i := 0; i < len(r.used); i++ {
+		if r.used[i] {
+			start = i + 1
+		} else if i-start+1 == sectors {
+			break
+		}
+	}
+
+	for len(r.used) < start+sectors {
+		r.used = append(r.used, false)
+	}
+	for i := start; i < start+sectors; i++ {
+		r.used[i] = true
+	}
+	return start
+}
+
+func (r *File) free(offset int, sectors int) {
+	for i := offset; i < offset+sectors && i < len(r.used); i++ {
+		r.used[i] = false
+	}
+}
+
+// markSectors marks the sectors of a chunk location as used or free.
+func (r *File) markSectors(location uint32, used bool) {
+	offset := int(location >> 8)
+	sectors := int(location & 0xff)
+	for len(r.used) < offset+sectors {
+		r.used = append(r.used, false)
+	}
+	for i := offset; i < offset+sectors; i++ {
+		r.used[i] = used
+	}
+}
+
+// compressChunk compresses data with the given compression type, at the given gzip or
+// zlib level. LZ4 has no levels.
+func compressChunk(data []byte, compression byte, level int) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	var err error
+	switch compression {
+	case CompressionGzip:
+		w, err = gzip.NewWriterLevel(buf, level)
+	case CompressionZlib:
+		w, err = zlib.NewWriterLevel(buf, level)
+	case CompressionNone:
+		return data, nil
+	case CompressionLZ4:
+		return lz4Encode(data), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression type %d", compression)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFileSync replaces the file name with data through a temporary file, so it is
+// never left half written.
+func writeFileSync(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
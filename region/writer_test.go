@@ -0,0 +1,178 @@
+package region
+
+import (
+	"bytes"
+	"errors"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/test"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// chunkData returns an NBT compound holding size bytes that do not compress.
+func chunkData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	return nbt.Marshal("", map[string]interface{}{"data": data})
+}
+
+func openRegion(t *testing.T, name string) *File {
+	t.Helper()
+
+	r, err := OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestWriteChunk(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.1.-1.mca")
+	r := openRegion(t, name)
+
+	chunks := []struct {
+		x, z        int
+		compression byte
+		data        []byte
+	}{
+		{x: 32, z: -32, compression: CompressionGzip, data: test.BigTestBytes},
+		{x: 33, z: -32, compression: CompressionZlib, data: test.BigTestBytes},
+		{x: 63, z: -1, compression: CompressionNone, data: chunkData(10000)},
+	}
+	before := time.Now().Add(-time.Second)
+	for _, c := range chunks {
+		if err := r.WriteChunk(c.x, c.z, c.data, c.compression); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	r = openRegion(t, name)
+	if got := len(r.Chunks()); got != len(chunks) {
+		t.Errorf("got %d chunks, want %d", got, len(chunks))
+	}
+	for _, c := range chunks {
+		got, err := r.ReadChunk(c.x, c.z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, c.data) {
+			t.Errorf("chunk %d,%d differs after reopening", c.x, c.z)
+		}
+		if r.Timestamp(c.x, c.z).Before(before) {
+			t.Errorf("chunk %d,%d has timestamp %v", c.x, c.z, r.Timestamp(c.x, c.z))
+		}
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size()%SectorSize != 0 {
+		t.Errorf("file size %d is not a multiple of the sector size", info.Size())
+	}
+}
+
+func TestSectorReuse(t *testing.T) {
+	r := openRegion(t, filepath.Join(t.TempDir(), "r.0.0.mca"))
+
+	write := func(x int, size int) {
+		t.Helper()
+		if err := r.WriteChunk(x, 0, chunkData(size), CompressionNone); err != nil {
+			t.Fatal(err)
+		}
+	}
+	offset := func(x int) int {
+		return int(r.locations[index(x, 0)] >> 8)
+	}
+
+	write(0, 100)
+	write(1, 100)
+	if offset(0) != 2 || offset(1) != 3 {
+		t.Fatalf("chunks at sectors %d and %d, want 2 and 3", offset(0), offset(1))
+	}
+
+	// Growing a chunk moves it, leaving its old sector free.
+	write(0, 3*SectorSize)
+	if offset(0) != 4 {
+		t.Errorf("grown chunk at sector %d, want 4", offset(0))
+	}
+	write(2, 100)
+	if offset(2) != 2 {
+		t.Errorf("new chunk at sector %d, want the freed sector 2", offset(2))
+	}
+
+	// Removing a chunk frees its sectors.
+	if err := r.RemoveChunk(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadChunk(0, 0); !errors.Is(err, ErrNotPresent) {
+		t.Errorf("ReadChunk of a removed chunk returned %v", err)
+	}
+	write(3, 2*SectorSize)
+	if offset(3) != 4 {
+		t.Errorf("new chunk at sector %d, want the freed sector 4", offset(3))
+	}
+}
+
+func TestWriteExternalChunk(t *testing.T) {
+	dir := t.TempDir()
+	r := openRegion(t, filepath.Join(dir, "r.-1.0.mca"))
+	mcc := filepath.Join(dir, "c.-1.5.mcc")
+
+	large := chunkData(maxSectors * SectorSize)
+	if err := r.WriteChunk(-1, 5, large, CompressionZlib); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(mcc); err != nil {
+		t.Errorf("oversized chunk was not written to %s: %v", mcc, err)
+	}
+	if sectors := r.locations[index(-1, 5)] & 0xff; sectors != 1 {
+		t.Errorf("oversized chunk takes %d sectors, want 1", sectors)
+	}
+	got, err := r.ReadChunk(-1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, large) {
+		t.Errorf("oversized chunk differs")
+	}
+
+	// Shrinking the chunk brings it back into the region file.
+	small := test.BigTestPartial{IntTest: 42, DoubleTest: 0.5}
+	if err = r.Marshal(-1, 5, small); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(mcc); !os.IsNotExist(err) {
+		t.Errorf("%s was not removed", mcc)
+	}
+	var partial test.BigTestPartial
+	if err = r.Unmarshal(-1, 5, reflect.ValueOf(&partial).Elem()); err != nil {
+		t.Fatal(err)
+	}
+	if partial != small {
+		t.Errorf("chunk differs after Marshal and Unmarshal")
+	}
+}
+
+func TestWriteReadOnly(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "r.0.0.mca")
+	openRegion(t, name).Close()
+
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err = r.WriteChunk(0, 0, test.BigTestBytes, CompressionZlib); err == nil {
+		t.Errorf("expected an error writing to a read-only region")
+	}
+	if r.HasChunk(0, 0) {
+		t.Errorf("failed write left the chunk present")
+	}
+}
@@ -0,0 +1,422 @@
+package nbt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Schema describes the values allowed at one place of a document. Zero fields impose
+// no constraint, so the zero Schema accepts anything.
+//
+// Schemas are declared as Go values or parsed from a file with ParseSchema, and
+// nested through Fields for compounds and Elem for lists and arrays.
+type Schema struct {
+	// Types lists the allowed tag types.
+	Types []TagType
+
+	// Required marks a compound entry that must be present.
+	Required bool
+
+	// Min and Max bound numeric values, including the elements of arrays.
+	Min *float64
+	Max *float64
+
+	// MinLen and MaxLen bound the length of strings, lists and arrays.
+	MinLen *int
+	MaxLen *int
+
+	// Pattern must match string values.
+	Pattern *regexp.Regexp
+
+	// Enum lists the allowed values. Integers match whatever their tag type, so an
+	// enum of ints also constrains byte and short fields.
+	Enum []interface{}
+
+	// Fields holds the schemas of compound entries. Entries without a schema are
+	// accepted unless Closed is set.
+	Fields map[string]*Schema
+	Closed bool
+
+	// Elem is the schema of every list or array element.
+	Elem *Schema
+}
+
+// Violation is a value that does not satisfy its schema.
+type Violation struct {
+	Path    Path
+	Message string
+}
+
+func (v Violation) String() string {
+	if len(v.Path.nodes) == 0 {
+		return v.Message
+	}
+	return v.Path.String() + ": " + v.Message
+}
+
+// Validate checks data against the schema and returns every violation, rather than
+// stopping at the first one. Values of an unexpected type are not checked further.
+func (s *Schema) Validate(data interface{}) []Violation {
+	tree, err := toTree(data)
+	if err != nil {
+		return []Violation{{Message: err.Error()}}
+	}
+	var violations []Violation
+	s.validate(Path{}, tree, &violations)
+	return violations
+}
+
+func (s *Schema) validate(path Path, data interface{}, violations *[]Violation) {
+	report := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	tagType := tagTypeOf(data)
+	if len(s.Types) > 0 {
+		allowed := false
+		for _, t := range s.Types {
+			allowed = allowed || t == tagType
+		}
+		if !allowed {
+			names := make([]string, len(s.Types))
+			for i, t := range s.Types {
+				names[i] = t.String()
+			}
+			report("expected %s, got %v", strings.Join(names, " or "), tagType)
+			return
+		}
+	}
+
+	if f, ok := numericValue(data); ok {
+		if s.Min != nil && f < *s.Min {
+			report("%v is less than the minimum %v", f, *s.Min)
+		}
+		if s.Max != nil && f > *s.Max {
+			report("%v is greater than the maximum %v", f, *s.Max)
+		}
+	}
+
+	if length, ok := lengthOf(data); ok {
+		if s.MinLen != nil && length < *s.MinLen {
+			report("length %d is less than the minimum %d", length, *s.MinLen)
+		}
+		if s.MaxLen != nil && length > *s.MaxLen {
+			report("length %d is greater than the maximum %d", length, *s.MaxLen)
+		}
+	}
+
+	if str, ok := data.(string); ok && s.Pattern != nil && !s.Pattern.MatchString(str) {
+		report("%q does not match %s", str, s.Pattern)
+	}
+
+	if len(s.Enum) > 0 {
+		allowed := false
+		for _, v := range s.Enum {
+			allowed = allowed || enumEqual(v, data)
+		}
+		if !allowed {
+			report("%s is not one of the allowed values", FormatSNBT(data))
+		}
+	}
+
+	switch tagType {
+	case TagCompound:
+		m := data.(map[string]interface{})
+
+		keys := make([]string, 0, len(s.Fields))
+		for key := range s.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value, ok := m[key]
+			if !ok {
+				if s.Fields[key].Required {
+					*violations = append(*violations, Violation{Path: path.child(key), Message: "missing required entry"})
+				}
+				continue
+			}
+			s.Fields[key].validate(path.child(key), value, violations)
+		}
+
+		if s.Closed {
+			unknown := make([]string, 0)
+			for key := range m {
+				if _, ok := s.Fields[key]; !ok {
+					unknown = append(unknown, key)
+				}
+			}
+			sort.Strings(unknown)
+			for _, key := range unknown {
+				*violations = append(*violations, Violation{Path: path.child(key), Message: "unexpected entry"})
+			}
+		}
+	case TagList, TagByteArray, TagIntArray, TagLongArray:
+		if s.Elem == nil {
+			return
+		}
+		list := reflect.ValueOf(data)
+		for i := 0; i < list.Len(); i++ {
+			s.Elem.validate(path.element(i), list.Index(i).Interface(), violations)
+		}
+	}
+}
+
+// numericValue returns the value of a byte, short, int, long, float or double.
+func numericValue(data interface{}) (float64, bool) {
+	switch v := data.(type) {
+	case byte:
+		return float64(int8(v)), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// integerValue returns the value of a byte, short, int or long, with bytes signed.
+func integerValue(data interface{}) (int64, bool) {
+	switch v := data.(type) {
+	case byte:
+		return int64(int8(v)), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// enumEqual reports whether data equals an enum value. Integers are compared by
+// value, as JSON schemas cannot tell a byte or short from an int.
+func enumEqual(allowed interface{}, data interface{}) bool {
+	a, ok := integerValue(allowed)
+	b, isInteger := integerValue(data)
+	if ok && isInteger {
+		return a == b
+	}
+	return reflect.DeepEqual(allowed, data)
+}
+
+// lengthOf returns the length of a string, list or array.
+func lengthOf(data interface{}) (int, bool) {
+	switch tagTypeOf(data) {
+	case TagString, TagList, TagByteArray, TagIntArray, TagLongArray:
+		return reflect.ValueOf(data).Len(), true
+	}
+	return 0, false
+}
+
+// ParseSchema parses a schema written in SNBT, or in JSON when s starts with {". JSON
+// numbers are ints when they are integers that fit, longs when they are larger
+// integers and doubles otherwise, and booleans are bytes, as in SNBT. Every schema is
+// a compound with these optional entries:
+//
+//	type      tag type name, such as "int" or "compound"
+//	types     list of allowed tag type names
+//	required  whether a compound entry must be present
+//	min, max  numeric bounds
+//	minLen    minimum length of strings, lists and arrays
+//	maxLen    maximum length of strings, lists and arrays
+//	pattern   regular expression strings must match
+//	enum      list of allowed values
+//	fields    compound of nested schemas for compound entries
+//	closed    whether entries missing from fields are rejected
+//	elem      schema of list and array elements
+//
+// Tag type names are the ones used by ToJSON.
+func ParseSchema(s string) (*Schema, error) {
+	var tree interface{}
+	var err error
+	if trimmed := strings.TrimLeftFunc(s, unicode.IsSpace); strings.HasPrefix(trimmed, "{") &&
+		strings.HasPrefix(strings.TrimLeftFunc(trimmed[1:], unicode.IsSpace), `"`) {
+		tree, err = parseJSONTree(s)
+	} else {
+		tree, err = ParseSNBT(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return schemaFromTree(Path{}, tree)
+}
+
+// parseJSONTree decodes plain JSON into a generic tree, with numbers and booleans
+// converted to the tags SNBT would give them.
+func parseJSONTree(s string) (interface{}, error) {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("schema: unexpected data after the JSON value")
+	}
+	return jsonTree(v)
+}
+
+func jsonTree(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			tree, err := jsonTree(nested)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = tree
+		}
+		return v, nil
+	case []interface{}:
+		for i, nested := range v {
+			tree, err := jsonTree(nested)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = tree
+		}
+		return v, nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return int32(i), nil
+			}
+			return i, nil
+		}
+		return v.Float64()
+	case bool:
+		if v {
+			return byte(1), nil
+		}
+		return byte(0), nil
+	case string:
+		return v, nil
+	}
+	return nil, errors.New("schema: null is not a valid value")
+}
+
+func schemaFromTree(path Path, tree interface{}) (*Schema, error) {
+	fail := func(key string, format string, args ...interface{}) error {
+		return fmt.Errorf("schema: %s: %s", path.child(key), fmt.Sprintf(format, args...))
+	}
+
+	m, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema: %s: expected a compound", path)
+	}
+
+	s := &Schema{}
+	for key, value := range m {
+		switch key {
+		case "type", "types":
+			names, ok := value.([]interface{})
+			if !ok {
+				names = []interface{}{value}
+			}
+			for _, name := range names {
+				t, ok := tagTypeByJSONName(name)
+				if !ok {
+					return nil, fail(key, "unknown tag type %v", name)
+				}
+				s.Types = append(s.Types, t)
+			}
+		case "required", "closed":
+			b, ok := value.(byte)
+			if !ok {
+				return nil, fail(key, "expected a boolean")
+			}
+			if key == "required" {
+				s.Required = b != 0
+			} else {
+				s.Closed = b != 0
+			}
+		case "min", "max":
+			f, ok := numericValue(value)
+			if !ok {
+				return nil, fail(key, "expected a number")
+			}
+			if key == "min" {
+				s.Min = &f
+			} else {
+				s.Max = &f
+			}
+		case "minLen", "maxLen":
+			f, ok := numericValue(value)
+			if !ok || f != float64(int(f)) {
+				return nil, fail(key, "expected an integer")
+			}
+			n := int(f)
+			if key == "minLen" {
+				s.MinLen = &n
+			} else {
+				s.MaxLen = &n
+			}
+		case "pattern":
+			str, ok := value.(string)
+			if !ok {
+				return nil, fail(key, "expected a string")
+			}
+			re, err := regexp.Compile(str)
+			if err != nil {
+				return nil, fail(key, "%v", err)
+			}
+			s.Pattern = re
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, fail(key, "expected a list")
+			}
+			s.Enum = values
+		case "fields":
+			fields, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fail(key, "expected a compound")
+			}
+			s.Fields = make(map[string]*Schema, len(fields))
+			for name, field := range fields {
+				nested, err := schemaFromTree(path.child(key).child(name), field)
+				if err != nil {
+					return nil, err
+				}
+				s.Fields[name] = nested
+			}
+		case "elem":
+			nested, err := schemaFromTree(path.child(key), value)
+			if err != nil {
+				return nil, err
+			}
+			s.Elem = nested
+		default:
+			return nil, fail(key, "unknown schema entry")
+		}
+	}
+	return s, nil
+}
+
+func tagTypeByJSONName(name interface{}) (TagType, bool) {
+	for t, n := range jsonTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return TagEnd, false
+}
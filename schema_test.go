@@ -0,0 +1,190 @@
+package nbt
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	min, max := 0.0, 20.0
+	maxLen := 2
+
+	schema := &Schema{
+		Types:  []TagType{TagCompound},
+		Closed: true,
+		Fields: map[string]*Schema{
+			"Health": {Types: []TagType{TagFloat}, Required: true, Min: &min, Max: &max},
+			"id":     {Types: []TagType{TagString}, Required: true, Pattern: regexp.MustCompile(`^minecraft:[a-z_]+$`)},
+			"Tags":   {Types: []TagType{TagList}, MaxLen: &maxLen, Elem: &Schema{Types: []TagType{TagString}}},
+			"Mode":   {Enum: []interface{}{int32(0), int32(1)}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "valid",
+			input: `{Health:20.0f,id:"minecraft:zombie",Tags:["a"],Mode:1}`,
+			want:  nil,
+		},
+		{
+			name:  "every violation is reported",
+			input: `{Health:21.0f,id:"Zombie",Tags:["a","b","c"],Mode:2,Extra:1b}`,
+			want: []string{
+				"Health: 21 is greater than the maximum 20",
+				"Mode: 2 is not one of the allowed values",
+				"Tags: length 3 is greater than the maximum 2",
+				`id: "Zombie" does not match ^minecraft:[a-z_]+$`,
+				"Extra: unexpected entry",
+			},
+		},
+		{
+			name:  "missing and mistyped",
+			input: `{Health:20,Tags:[1]}`,
+			want: []string{
+				"Health: expected TAG_Float, got TAG_Int",
+				"Tags[0]: expected TAG_String, got TAG_Int",
+				"id: missing required entry",
+			},
+		},
+		{
+			name:  "root type",
+			input: `[]`,
+			want:  []string{"expected TAG_Compound, got TAG_List"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := schema.Validate(mustParseSNBT(t, tt.input))
+
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %q, want %q", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		data    string
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "snbt",
+			input: `{type:"compound",fields:{Pos:{type:"list",required:1b,minLen:3,maxLen:3,elem:{type:"double"}}}}`,
+			data:  `{Pos:[1.0d,2.0d]}`,
+			want:  1,
+		},
+		{
+			name:  "json",
+			input: `{"type": "compound", "closed": true, "fields": {"UUID": {"type": "int_array", "minLen": 4, "maxLen": 4, "elem": {"min": 0}}}}`,
+			data:  `{UUID:[I;1,-2,3,4],Other:1}`,
+			want:  2,
+		},
+		{
+			name:  "json large integer",
+			input: `{"type":"long","max":3000000000}`,
+			data:  `3000000001L`,
+			want:  1,
+		},
+		{
+			name:  "json exponent",
+			input: `{"type":"int","max":1e5}`,
+			data:  `100001`,
+			want:  1,
+		},
+		{
+			name:  "json escape",
+			input: `{"type":"string","pattern":"^a\tb$"}`,
+			data:  "\"a\tb\"",
+			want:  0,
+		},
+		{
+			name:  "json escape mismatch",
+			input: `{"type":"string","pattern":"^a\tb$"}`,
+			data:  `"atb"`,
+			want:  1,
+		},
+		{
+			name:  "json enum",
+			input: `{"fields":{"a":{"enum":[1,3000000000]},"b":{"enum":[1,3000000000]},"c":{"enum":[true]}}}`,
+			data:  `{a:1,b:3000000000L,c:1b}`,
+			want:  0,
+		},
+		{
+			name:  "json byte and short enum",
+			input: `{"fields":{"a":{"enum":[1,2]},"b":{"enum":[-1]},"c":{"enum":[300]}}}`,
+			data:  `{a:2b,b:-1b,c:300s}`,
+			want:  0,
+		},
+		{
+			name:  "json byte enum mismatch",
+			input: `{"fields":{"a":{"enum":[1,2]}}}`,
+			data:  `{a:3b}`,
+			want:  1,
+		},
+		{
+			name:    "json null",
+			input:   `{"min":null}`,
+			wantErr: true,
+		},
+		{
+			name:    "json trailing data",
+			input:   `{"min":1} {}`,
+			wantErr: true,
+		},
+		{
+			name:  "multiple types",
+			input: `{types:["int","long"]}`,
+			data:  `1L`,
+			want:  0,
+		},
+		{
+			name:    "unknown type",
+			input:   `{type:"uuid"}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown entry",
+			input:   `{fields:{a:{optional:1b}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			input:   `{pattern:"("}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := ParseSchema(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := schema.Validate(mustParseSNBT(t, tt.data)); len(got) != tt.want {
+				t.Errorf("got %v, want %d violations", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package nbt
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	snbtDoubleNoSuffix = regexp.MustCompile(`(?i)^[-+]?(?:[0-9]+[.]|[0-9]*[.][0-9]+)(?:e[-+]?[0-9]+)?$`)
+	snbtDouble         = regexp.MustCompile(`(?i)^[-+]?(?:[0-9]+[.]?|[0-9]*[.][0-9]+)(?:e[-+]?[0-9]+)?d$`)
+	snbtFloat          = regexp.MustCompile(`(?i)^[-+]?(?:[0-9]+[.]?|[0-9]*[.][0-9]+)(?:e[-+]?[0-9]+)?f$`)
+	snbtByte           = regexp.MustCompile(`(?i)^[-+]?(?:0|[1-9][0-9]*)b$`)
+	snbtLong           = regexp.MustCompile(`(?i)^[-+]?(?:0|[1-9][0-9]*)l$`)
+	snbtShort          = regexp.MustCompile(`(?i)^[-+]?(?:0|[1-9][0-9]*)s$`)
+	snbtInt            = regexp.MustCompile(`^[-+]?(?:0|[1-9][0-9]*)$`)
+	snbtUnquoted       = regexp.MustCompile(`^[0-9A-Za-z_\-.+]+$`)
+)
+
+// ParseSNBT parses stringified NBT, as used in Minecraft commands, into the same Go
+// values Unmarshal produces when decoding into an interface{}: byte, int16, int32,
+// int64, float32, float64, string, []byte, []int32, []int64, []interface{} and
+// map[string]interface{}.
+func ParseSNBT(s string) (interface{}, error) {
+	p := &snbtParser{s: s}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		return nil, p.errorf("trailing data")
+	}
+	return v, nil
+}
+
+type snbtParser struct {
+	s   string
+	pos int
+}
+
+func (p *snbtParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("snbt: %s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *snbtParser) skipSpace() {
+	for p.pos < len(p.s) && strings.ContainsRune(" \t\r\n", rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *snbtParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *snbtParser) expect(c byte) error {
+	if p.peek() != c {
+		return p.errorf("expected '%c'", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *snbtParser) value() (interface{}, error) {
+	switch p.peek() {
+	case '{':
+		return p.compound()
+	case '[':
+		if p.pos+2 < len(p.s) && p.s[p.pos+2] == ';' && strings.ContainsRune("BIL", rune(p.s[p.pos+1])) {
+			return p.array()
+		}
+		return p.list()
+	case '"', '\'':
+		return p.quoted()
+	case 0:
+		return nil, p.errorf("expected value")
+	}
+
+	token := p.unquoted()
+	if token == "" {
+		return nil, p.errorf("expected value")
+	}
+	return parseSNBTScalar(token), nil
+}
+
+func (p *snbtParser) unquoted() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && isSNBTUnquotedChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func isSNBTUnquotedChar(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || strings.IndexByte("_-.+", c) >= 0
+}
+
+func (p *snbtParser) quoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+
+	sb := strings.Builder{}
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch c {
+		case '\\':
+			if p.pos >= len(p.s) {
+				return "", p.errorf("unterminated escape")
+			}
+			sb.WriteByte(p.s[p.pos])
+			p.pos++
+		case quote:
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *snbtParser) key() (string, error) {
+	switch p.peek() {
+	case '"', '\'':
+		return p.quoted()
+	}
+	key := p.unquoted()
+	if key == "" {
+		return "", p.errorf("expected key")
+	}
+	return key, nil
+}
+
+func (p *snbtParser) compound() (interface{}, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	for p.peek() != '}' {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(':'); err != nil {
+			return nil, err
+		}
+		m[key], err = p.value()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return m, p.expect('}')
+}
+
+func (p *snbtParser) list() (interface{}, error) {
+	if err := p.expect('['); err != nil {
+		return nil, err
+	}
+
+	list := make([]interface{}, 0)
+	for p.peek() != ']' {
+		start := p.pos
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if len(list) > 0 && typeOf(reflect.TypeOf(v)) != typeOf(reflect.TypeOf(list[0])) {
+			p.pos = start
+			return nil, p.errorf("cannot insert %v into a list of %v", typeOf(reflect.TypeOf(v)), typeOf(reflect.TypeOf(list[0])))
+		}
+		list = append(list, v)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	return list, p.expect(']')
+}
+
+func (p *snbtParser) array() (interface{}, error) {
+	p.skipSpace()
+	arrayType := p.s[p.pos+1]
+	p.pos += 3
+
+	var values []interface{}
+	for p.peek() != ']' {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+
+	switch arrayType {
+	case 'B':
+		array := make([]byte, len(values))
+		for i, v := range values {
+			b, ok := v.(byte)
+			if !ok {
+				return nil, p.errorf("invalid byte array element %v", v)
+			}
+			array[i] = b
+		}
+		return array, nil
+	case 'I':
+		array := make([]int32, len(values))
+		for i, v := range values {
+			n, ok := v.(int32)
+			if !ok {
+				return nil, p.errorf("invalid int array element %v", v)
+			}
+			array[i] = n
+		}
+		return array, nil
+	default:
+		array := make([]int64, len(values))
+		for i, v := range values {
+			switch n := v.(type) {
+			case int64:
+				array[i] = n
+			case int32:
+				array[i] = int64(n)
+			default:
+				return nil, p.errorf("invalid long array element %v", v)
+			}
+		}
+		return array, nil
+	}
+}
+
+// parseSNBTScalar interprets an unquoted token as a number, boolean or string,
+// following the rules of the vanilla parser.
+func parseSNBTScalar(token string) interface{} {
+	trimSuffix := func(s string) string { return s[:len(s)-1] }
+
+	switch {
+	case snbtFloat.MatchString(token):
+		if v, err := strconv.ParseFloat(trimSuffix(token), 32); err == nil {
+			return float32(v)
+		}
+	case snbtByte.MatchString(token):
+		if v, err := strconv.ParseInt(trimSuffix(token), 10, 8); err == nil {
+			return byte(v)
+		}
+	case snbtLong.MatchString(token):
+		if v, err := strconv.ParseInt(trimSuffix(token), 10, 64); err == nil {
+			return v
+		}
+	case snbtShort.MatchString(token):
+		if v, err := strconv.ParseInt(trimSuffix(token), 10, 16); err == nil {
+			return int16(v)
+		}
+	case snbtInt.MatchString(token):
+		if v, err := strconv.ParseInt(token, 10, 32); err == nil {
+			return int32(v)
+		}
+	case snbtDouble.MatchString(token):
+		if v, err := strconv.ParseFloat(trimSuffix(token), 64); err == nil {
+			return v
+		}
+	case snbtDoubleNoSuffix.MatchString(token):
+		if v, err := strconv.ParseFloat(token, 64); err == nil {
+			return v
+		}
+	case strings.EqualFold(token, "true"):
+		return byte(1)
+	case strings.EqualFold(token, "false"):
+		return byte(0)
+	}
+	return token
+}
+
+// FormatSNBT formats a value as stringified NBT. Compound keys are sorted so the
+// output is deterministic.
+func FormatSNBT(v interface{}) string {
+	sb := &strings.Builder{}
+	formatSNBT(sb, reflect.ValueOf(v))
+	return sb.String()
+}
+
+func formatSNBT(sb *strings.Builder, v reflect.Value) {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		sb.WriteString("{}")
+		return
+	}
+
+	switch typeOf(v.Type()) {
+	case TagByte:
+		if v.Kind() == reflect.Bool {
+			if v.Bool() {
+				sb.WriteString("1b")
+			} else {
+				sb.WriteString("0b")
+			}
+			return
+		}
+		sb.WriteString(strconv.Itoa(int(int8(v.Uint()))) + "b")
+	case TagShort:
+		sb.WriteString(strconv.FormatInt(signedValue(v), 10) + "s")
+	case TagInt:
+		sb.WriteString(strconv.FormatInt(signedValue(v), 10))
+	case TagLong:
+		sb.WriteString(strconv.FormatInt(signedValue(v), 10) + "L")
+	case TagFloat:
+		sb.WriteString(formatSNBTFloat(v.Float(), 32) + "f")
+	case TagDouble:
+		sb.WriteString(formatSNBTFloat(v.Float(), 64) + "d")
+	case TagString:
+		sb.WriteString(quoteSNBT(v.String()))
+	case TagByteArray:
+		formatSNBTArray(sb, "B;", "b", v)
+	case TagIntArray:
+		formatSNBTArray(sb, "I;", "", v)
+	case TagLongArray:
+		formatSNBTArray(sb, "L;", "L", v)
+	case TagList:
+		sb.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			formatSNBT(sb, v.Index(i))
+		}
+		sb.WriteByte(']')
+	case TagCompound:
+		if v.Kind() == reflect.Struct {
+			tree, err := toTree(v.Interface())
+			if err != nil {
+				sb.WriteString(quoteSNBT(fmt.Sprint(v.Interface())))
+				return
+			}
+			formatSNBT(sb, reflect.ValueOf(tree))
+			return
+		}
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(formatSNBTKey(k))
+			sb.WriteByte(':')
+			formatSNBT(sb, v.MapIndex(reflect.ValueOf(k)))
+		}
+		sb.WriteByte('}')
+	default:
+		sb.WriteString(quoteSNBT(fmt.Sprint(v.Interface())))
+	}
+}
+
+func formatSNBTArray(sb *strings.Builder, prefix string, suffix string, v reflect.Value) {
+	sb.WriteByte('[')
+	sb.WriteString(prefix)
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		if v.Index(i).Kind() == reflect.Uint8 {
+			sb.WriteString(strconv.Itoa(int(int8(v.Index(i).Uint()))))
+		} else {
+			sb.WriteString(strconv.FormatInt(v.Index(i).Int(), 10))
+		}
+		sb.WriteString(suffix)
+	}
+	sb.WriteByte(']')
+}
+
+func formatSNBTFloat(f float64, bitSize int) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "0"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func formatSNBTKey(k string) string {
+	if snbtUnquoted.MatchString(k) {
+		return k
+	}
+	return quoteSNBT(k)
+}
+
+func quoteSNBT(s string) string {
+	sb := strings.Builder{}
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// signedValue returns the integer held by v, reinterpreting unsigned kinds as the
+// signed values they encode on the wire.
+func signedValue(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Uint8:
+		return int64(int8(v.Uint()))
+	case reflect.Uint16:
+		return int64(int16(v.Uint()))
+	case reflect.Uint32:
+		return int64(int32(v.Uint()))
+	case reflect.Uint, reflect.Uint64:
+		return int64(v.Uint())
+	}
+	return v.Int()
+}
@@ -0,0 +1,125 @@
+package nbt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSNBT(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:  "numbers",
+			input: `{b:-1b,s:2s,i:3,l:4L,f:0.5f,d:0.25,d2:1d,bool:true}`,
+			want: map[string]interface{}{
+				"b":    byte(0xFF),
+				"s":    int16(2),
+				"i":    int32(3),
+				"l":    int64(4),
+				"f":    float32(0.5),
+				"d":    0.25,
+				"d2":   1.0,
+				"bool": byte(1),
+			},
+		},
+		{
+			name:  "strings",
+			input: `{unquoted:hello,"quoted key":"a \"b\"",single:'c'}`,
+			want: map[string]interface{}{
+				"unquoted":   "hello",
+				"quoted key": `a "b"`,
+				"single":     "c",
+			},
+		},
+		{
+			name:  "lists and arrays",
+			input: `{list:[1,2],empty:[],bytes:[B;1b,2b],ints:[I;1,2],longs:[L;1L,2L],nested:[{a:1}]}`,
+			want: map[string]interface{}{
+				"list":   []interface{}{int32(1), int32(2)},
+				"empty":  []interface{}{},
+				"bytes":  []byte{1, 2},
+				"ints":   []int32{1, 2},
+				"longs":  []int64{1, 2},
+				"nested": []interface{}{map[string]interface{}{"a": int32(1)}},
+			},
+		},
+		{
+			name:    "mixed list",
+			input:   `[1,2b]`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated compound",
+			input:   `{a:1`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			input:   `{a:1}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSNBT(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSNBT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got:\n%#v\nwant:\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSNBT(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+		want  string
+	}{
+		{
+			name: "compound",
+			input: map[string]interface{}{
+				"b":          byte(0xFF),
+				"s":          int16(2),
+				"i":          int32(3),
+				"l":          int64(4),
+				"f":          float32(0.5),
+				"d":          1.0,
+				"quoted key": "a \"b\"",
+			},
+			want: `{b:-1b,d:1.0d,f:0.5f,i:3,l:4L,"quoted key":"a \"b\"",s:2s}`,
+		},
+		{
+			name:  "lists and arrays",
+			input: []interface{}{[]byte{1}, []byte{}},
+			want:  `[[B;1b],[B;]]`,
+		},
+		{
+			name: "struct",
+			input: struct {
+				Name string `nbt:"name"`
+			}{Name: "Bananrama"},
+			want: `{name:"Bananrama"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatSNBT(tt.input)
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+
+			if _, err := ParseSNBT(got); err != nil {
+				t.Errorf("ParseSNBT() error = %v", err)
+			}
+		})
+	}
+}
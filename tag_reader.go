@@ -1,14 +1,13 @@
 package nbt
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"reflect"
 )
 
-func readTagByte(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := buf.ReadByte()
+func readTagByte(r reader, v reflect.Value) (err error) {
+	value, err := r.ReadByte()
 	if err != nil {
 		return
 	}
@@ -36,8 +35,8 @@ func readTagByte(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagShort(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := readInt16(buf)
+func readTagShort(r reader, v reflect.Value) (err error) {
+	value, err := readInt16(r)
 	if err != nil {
 		return
 	}
@@ -55,8 +54,8 @@ func readTagShort(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagInt(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := readInt32(buf)
+func readTagInt(r reader, v reflect.Value) (err error) {
+	value, err := readInt32(r)
 	if err != nil {
 		return
 	}
@@ -74,8 +73,8 @@ func readTagInt(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagLong(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := readInt64(buf)
+func readTagLong(r reader, v reflect.Value) (err error) {
+	value, err := readInt64(r)
 	if err != nil {
 		return
 	}
@@ -93,8 +92,8 @@ func readTagLong(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagFloat(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := readFloat32(buf)
+func readTagFloat(r reader, v reflect.Value) (err error) {
+	value, err := readFloat32(r)
 	if err != nil {
 		return
 	}
@@ -110,8 +109,8 @@ func readTagFloat(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagDouble(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := readFloat64(buf)
+func readTagDouble(r reader, v reflect.Value) (err error) {
+	value, err := readFloat64(r)
 	if err != nil {
 		return
 	}
@@ -127,8 +126,8 @@ func readTagDouble(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagString(buf *bytes.Buffer, v reflect.Value) (err error) {
-	value, err := readString(buf)
+func readTagString(r reader, v reflect.Value) (err error) {
+	value, err := readString(r)
 	if err != nil {
 		return
 	}
@@ -146,13 +145,13 @@ func readTagString(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagList(buf *bytes.Buffer, v reflect.Value) (err error) {
-	listType, err := readTagType(buf)
+func readTagList(r reader, v reflect.Value) (err error) {
+	listType, err := readTagType(r)
 	if err != nil {
 		return
 	}
 
-	length, err := readInt32(buf)
+	length, err := readInt32(r)
 	if err != nil {
 		return
 	}
@@ -163,8 +162,15 @@ func readTagList(buf *bytes.Buffer, v reflect.Value) (err error) {
 
 	switch v.Kind() {
 	case reflect.Interface:
-		v.Set(reflect.ValueOf(make([]interface{}, length)))
-		break
+		list := reflect.ValueOf(make([]interface{}, length))
+		for i := 0; i < int(length); i++ {
+			err = readValue(r, listType, list.Index(i))
+			if err != nil {
+				return
+			}
+		}
+		v.Set(list)
+		return
 	case reflect.Slice:
 		v.Set(reflect.MakeSlice(v.Type(), int(length), int(length)))
 		break
@@ -175,7 +181,7 @@ func readTagList(buf *bytes.Buffer, v reflect.Value) (err error) {
 	}
 
 	for i := 0; i < int(length); i++ {
-		err = readValue(buf, listType, v.Index(i))
+		err = readValue(r, listType, v.Index(i))
 		if err != nil {
 			return
 		}
@@ -183,49 +189,59 @@ func readTagList(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagCompoundStruct(buf *bytes.Buffer, v reflect.Value) (err error) {
+func readTagCompoundStruct(r reader, v reflect.Value) (err error) {
 	for {
-		var cmpTagType namedTagType
+		var cmpTagType TagType
 		var cmpTagName string
 
-		cmpTagType, err = readTagType(buf)
+		cmpTagType, err = readTagType(r)
 		if err != nil {
 			return
 		}
 
-		if cmpTagType == tagEnd {
+		if cmpTagType == TagEnd {
 			break
 		}
 
-		cmpTagName, err = readString(buf)
+		cmpTagName, err = readString(r)
 		if err != nil {
 			return
 		}
 
+		found := false
 		for i := 0; i < v.NumField(); i++ {
 			f := v.Type().Field(i)
-			tagName := f.Tag.Get("nbt")
-			if tagName == "-" {
+			if f.PkgPath != "" {
 				continue
 			}
 
-			if tagName == "" {
-				tagName = f.Name
+			tagName, _ := parseFieldTag(f)
+			if tagName == "-" {
+				continue
 			}
 
 			if tagName == cmpTagName {
-				err = readValue(buf, cmpTagType, v.Field(i))
+				err = readValue(r, cmpTagType, v.Field(i))
 				if err != nil {
 					return
 				}
+				found = true
 				break
 			}
 		}
+
+		// Skip tags without a matching field so the following ones are still read
+		if !found {
+			err = skipValue(r, cmpTagType)
+			if err != nil {
+				return
+			}
+		}
 	}
 	return
 }
 
-func readTagCompoundMap(buf *bytes.Buffer, v reflect.Value) (err error) {
+func readTagCompoundMap(r reader, v reflect.Value) (err error) {
 	if v.Type().Key().Kind() != reflect.String {
 		return errors.New("map key should be of type string")
 	}
@@ -235,19 +251,19 @@ func readTagCompoundMap(buf *bytes.Buffer, v reflect.Value) (err error) {
 	}
 
 	for {
-		var cmpTagType namedTagType
+		var cmpTagType TagType
 		var cmpTagName string
 
-		cmpTagType, err = readTagType(buf)
+		cmpTagType, err = readTagType(r)
 		if err != nil {
 			return
 		}
 
-		if cmpTagType == tagEnd {
+		if cmpTagType == TagEnd {
 			break
 		}
 
-		cmpTagName, err = readString(buf)
+		cmpTagName, err = readString(r)
 		if err != nil {
 			return
 		}
@@ -255,45 +271,45 @@ func readTagCompoundMap(buf *bytes.Buffer, v reflect.Value) (err error) {
 		var val interface{}
 
 		switch cmpTagType {
-		case tagByte:
+		case TagByte:
 			val = byte(0)
 			break
-		case tagShort:
+		case TagShort:
 			val = int16(0)
 			break
-		case tagInt:
+		case TagInt:
 			val = int32(0)
 			break
-		case tagLong:
+		case TagLong:
 			val = int64(0)
 			break
-		case tagFloat:
+		case TagFloat:
 			val = float32(0)
 			break
-		case tagDouble:
+		case TagDouble:
 			val = float64(0)
 			break
-		case tagString:
+		case TagString:
 			val = ""
 			break
-		case tagList:
+		case TagList:
 			val = make([]interface{}, 0)
 			break
-		case tagCompound:
+		case TagCompound:
 			val = make(map[string]interface{})
 			break
-		case tagByteArray:
+		case TagByteArray:
 			val = make([]byte, 0)
 			break
-		case tagIntArray:
+		case TagIntArray:
 			val = make([]int32, 0)
 			break
-		case tagLongArray:
+		case TagLongArray:
 			val = make([]int64, 0)
 			break
 		}
 
-		err = readValue(buf, cmpTagType, reflect.ValueOf(&val).Elem())
+		err = readValue(r, cmpTagType, reflect.ValueOf(&val).Elem())
 		if err != nil {
 			return err
 		}
@@ -302,8 +318,8 @@ func readTagCompoundMap(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagByteArray(buf *bytes.Buffer, v reflect.Value) (err error) {
-	b, err := readByteSlice(buf)
+func readTagByteArray(r reader, v reflect.Value) (err error) {
+	b, err := readByteSlice(r)
 	if err != nil {
 		return
 	}
@@ -316,8 +332,8 @@ func readTagByteArray(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagIntArray(buf *bytes.Buffer, v reflect.Value) (err error) {
-	b, err := readInt32Slice(buf)
+func readTagIntArray(r reader, v reflect.Value) (err error) {
+	b, err := readInt32Slice(r)
 	if err != nil {
 		return
 	}
@@ -325,8 +341,8 @@ func readTagIntArray(buf *bytes.Buffer, v reflect.Value) (err error) {
 	return
 }
 
-func readTagLongArray(buf *bytes.Buffer, v reflect.Value) (err error) {
-	b, err := readInt64Slice(buf)
+func readTagLongArray(r reader, v reflect.Value) (err error) {
+	b, err := readInt64Slice(r)
 	if err != nil {
 		return
 	}
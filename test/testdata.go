@@ -9,6 +9,24 @@ type Bananrama struct {
 	Name string `nbt:"name"`
 }
 
+type BananramaOmitEmpty struct {
+	Name  string      `nbt:"name"`
+	Count int32       `nbt:"count,omitempty"`
+	Tags  []string    `nbt:"tags,omitempty"`
+	Extra interface{} `nbt:"extra,omitempty"`
+}
+
+type BananramaOptional struct {
+	Name  *string    `nbt:"name,omitempty"`
+	Count *int32     `nbt:"count,omitempty"`
+	Owner *Bananrama `nbt:"owner,omitempty"`
+}
+
+type BigTestPartial struct {
+	IntTest    int32   `nbt:"intTest"`
+	DoubleTest float64 `nbt:"doubleTest"`
+}
+
 type BigTest struct {
 	LongTest      int64              `nbt:"longTest"`
 	ShortTest     int16              `nbt:"shortTest"`
@@ -62,6 +80,54 @@ var (
 		0x65, 0x00, 0x09, 0x42, 0x61, 0x6E, 0x61, 0x6E, 0x72, 0x61, 0x6D, 0x61, 0x00,
 	}
 
+	// BigTestBytes is the bigtest.nbt file of the NBT specification, uncompressed. As
+	// printed by nbt.DumpOptions{Name: "Level", MaxArrayLen: 5}.Dump:
+	//
+	//	TAG_Compound('Level'): 11 entries
+	//	{
+	//	  TAG_Byte_Array('byteArrayTest (the first 1000 values of (n*n*255+n*7)%100, starting with n=0 (0, 62, 34, 16, 8, ...))'): [1000 bytes] 0, 62, 34, 16, 8, ...
+	//	  TAG_Byte('byteTest'): 127
+	//	  TAG_Double('doubleTest'): 0.4931287132182315
+	//	  TAG_Float('floatTest'): 0.49823147
+	//	  TAG_Int('intTest'): 2147483647
+	//	  TAG_List('listTest (compound)'): 2 entries
+	//	  {
+	//	    TAG_Compound(None): 2 entries
+	//	    {
+	//	      TAG_Long('created-on'): 1264099775885
+	//	      TAG_String('name'): Compound tag #0
+	//	    }
+	//	    TAG_Compound(None): 2 entries
+	//	    {
+	//	      TAG_Long('created-on'): 1264099775885
+	//	      TAG_String('name'): Compound tag #1
+	//	    }
+	//	  }
+	//	  TAG_List('listTest (long)'): 5 entries
+	//	  {
+	//	    TAG_Long(None): 11
+	//	    TAG_Long(None): 12
+	//	    TAG_Long(None): 13
+	//	    TAG_Long(None): 14
+	//	    TAG_Long(None): 15
+	//	  }
+	//	  TAG_Long('longTest'): 9223372036854775807
+	//	  TAG_Compound('nested compound test'): 2 entries
+	//	  {
+	//	    TAG_Compound('egg'): 2 entries
+	//	    {
+	//	      TAG_String('name'): Eggbert
+	//	      TAG_Float('value'): 0.5
+	//	    }
+	//	    TAG_Compound('ham'): 2 entries
+	//	    {
+	//	      TAG_String('name'): Hampus
+	//	      TAG_Float('value'): 0.75
+	//	    }
+	//	  }
+	//	  TAG_Short('shortTest'): 32767
+	//	  TAG_String('stringTest'): HELLO WORLD THIS IS A TEST STRING ÅÄÖ!
+	//	}
 	BigTestBytes = []byte{
 		0x0A, 0x00, 0x05, 0x4C, 0x65, 0x76, 0x65, 0x6C, 0x04, 0x00, 0x08, 0x6C, 0x6F, 0x6E, 0x67, 0x54, 0x65, 0x73, 0x74, 0x7F,
 		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x02, 0x00, 0x09, 0x73, 0x68, 0x6F, 0x72, 0x74, 0x54, 0x65, 0x73, 0x74, 0x7F,
@@ -0,0 +1,248 @@
+package nbt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Token is one of CompoundStart, ListStart, Value or End.
+type Token interface{}
+
+// CompoundStart opens a TAG_Compound. Its entries follow until the matching End.
+type CompoundStart struct {
+	Name string
+}
+
+// ListStart opens a TAG_List of Len elements of ElemType, followed by the matching End.
+type ListStart struct {
+	Name     string
+	ElemType TagType
+	Len      int
+}
+
+// Value holds any tag that is not a compound or a list. Value has the same Go type
+// Unmarshal produces when decoding into an interface{}.
+type Value struct {
+	Name  string
+	Type  TagType
+	Value interface{}
+}
+
+// End closes the most recent CompoundStart or ListStart.
+type End struct{}
+
+type decoderFrame struct {
+	list      bool
+	elemType  TagType
+	remaining int
+}
+
+// Decoder reads a stream of named binary tags token by token, without materializing
+// the decoded data.
+type Decoder struct {
+	r     reader
+	stack []decoderFrame
+}
+
+// NewDecoder returns a decoder reading from r, buffering it unless it already
+// implements io.ByteReader.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br}
+}
+
+// Token returns the next token in the stream. Once a root tag has been closed, the
+// next call starts reading another root tag, returning io.EOF at the end of input.
+func (d *Decoder) Token() (Token, error) {
+	if len(d.stack) == 0 {
+		tagType, err := readTagType(d.r)
+		if err != nil {
+			return nil, err
+		}
+		if tagType == TagEnd {
+			return End{}, nil
+		}
+		name, err := readString(d.r)
+		if err != nil {
+			return nil, err
+		}
+		return d.open(tagType, name)
+	}
+
+	top := &d.stack[len(d.stack)-1]
+	if top.list {
+		if top.remaining <= 0 {
+			d.stack = d.stack[:len(d.stack)-1]
+			return End{}, nil
+		}
+		top.remaining--
+		return d.open(top.elemType, "")
+	}
+
+	tagType, err := readTagType(d.r)
+	if err != nil {
+		return nil, err
+	}
+	if tagType == TagEnd {
+		d.stack = d.stack[:len(d.stack)-1]
+		return End{}, nil
+	}
+	name, err := readString(d.r)
+	if err != nil {
+		return nil, err
+	}
+	return d.open(tagType, name)
+}
+
+// Skip discards the rest of the innermost open compound or list, including its End
+// token. It is a no-op when no container is open.
+func (d *Decoder) Skip() error {
+	if len(d.stack) == 0 {
+		return nil
+	}
+
+	top := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+	if top.list {
+		for i := 0; i < top.remaining; i++ {
+			if err := skipValue(d.r, top.elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return skipCompound(d.r)
+}
+
+func (d *Decoder) open(tagType TagType, name string) (Token, error) {
+	switch tagType {
+	case TagCompound:
+		d.stack = append(d.stack, decoderFrame{})
+		return CompoundStart{Name: name}, nil
+	case TagList:
+		elemType, err := readTagType(d.r)
+		if err != nil {
+			return nil, err
+		}
+		length, err := readInt32(d.r)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			length = 0
+		}
+		d.stack = append(d.stack, decoderFrame{list: true, elemType: elemType, remaining: int(length)})
+		return ListStart{Name: name, ElemType: elemType, Len: int(length)}, nil
+	}
+
+	value, err := readScalar(d.r, tagType)
+	if err != nil {
+		return nil, err
+	}
+	return Value{Name: name, Type: tagType, Value: value}, nil
+}
+
+func readScalar(r reader, tagType TagType) (interface{}, error) {
+	switch tagType {
+	case TagByte:
+		return r.ReadByte()
+	case TagShort:
+		return readInt16(r)
+	case TagInt:
+		return readInt32(r)
+	case TagLong:
+		return readInt64(r)
+	case TagFloat:
+		return readFloat32(r)
+	case TagDouble:
+		return readFloat64(r)
+	case TagString:
+		return readString(r)
+	case TagByteArray:
+		return readByteSlice(r)
+	case TagIntArray:
+		return readInt32Slice(r)
+	case TagLongArray:
+		return readInt64Slice(r)
+	}
+	return nil, fmt.Errorf("cannot read %v as a scalar", tagType)
+}
+
+func skipValue(r reader, tagType TagType) error {
+	var n int64
+	switch tagType {
+	case TagByte:
+		n = 1
+	case TagShort:
+		n = 2
+	case TagInt, TagFloat:
+		n = 4
+	case TagLong, TagDouble:
+		n = 8
+	case TagString:
+		length, err := readUInt16(r)
+		if err != nil {
+			return err
+		}
+		n = int64(length)
+	case TagByteArray, TagIntArray, TagLongArray:
+		length, err := readInt32(r)
+		if err != nil {
+			return err
+		}
+		n = int64(length)
+		if tagType == TagIntArray {
+			n *= 4
+		} else if tagType == TagLongArray {
+			n *= 8
+		}
+	case TagList:
+		elemType, err := readTagType(r)
+		if err != nil {
+			return err
+		}
+		length, err := readInt32(r)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(length); i++ {
+			if err = skipValue(r, elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case TagCompound:
+		return skipCompound(r)
+	default:
+		return fmt.Errorf("unknown tag type %v", tagType)
+	}
+
+	if n < 0 {
+		return errors.New("negative length")
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	return err
+}
+
+func skipCompound(r reader) error {
+	for {
+		tagType, err := readTagType(r)
+		if err != nil {
+			return err
+		}
+		if tagType == TagEnd {
+			return nil
+		}
+		if _, err = readString(r); err != nil {
+			return err
+		}
+		if err = skipValue(r, tagType); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt/test"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestDecoderToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  []Token
+	}{
+		{
+			name:  "unnamed root compound tag",
+			input: test.UnnamedRootCompoundBytes,
+			want: []Token{
+				CompoundStart{Name: ""},
+				Value{Name: "ByteTag", Type: TagByte, Value: byte(0xFF)},
+				Value{Name: "StringTag", Type: TagString, Value: "hello, world"},
+				End{},
+			},
+		},
+		{
+			name:  "bananrama",
+			input: test.BananramaBytes,
+			want: []Token{
+				CompoundStart{Name: "hello world"},
+				Value{Name: "name", Type: TagString, Value: "Bananrama"},
+				End{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDecoder(bytes.NewReader(tt.input))
+
+			var got []Token
+			for {
+				tok, err := d.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Token() error = %v", err)
+				}
+				got = append(got, tok)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecoderSkip(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(test.BigTestBytes))
+
+	var found interface{}
+	for found == nil {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+
+		switch tok := tok.(type) {
+		case CompoundStart:
+			if tok.Name == "nested compound test" {
+				if err = d.Skip(); err != nil {
+					t.Fatalf("Skip() error = %v", err)
+				}
+			}
+		case ListStart:
+			if err = d.Skip(); err != nil {
+				t.Fatalf("Skip() error = %v", err)
+			}
+		case Value:
+			if tok.Name == "doubleTest" {
+				found = tok.Value
+			}
+		}
+	}
+
+	if found != 0.49312871321823148 {
+		t.Errorf("got %v, want %v", found, 0.49312871321823148)
+	}
+}
+
+func TestDecoderArrayLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{
+			name:  "negative length",
+			input: []byte{0x07, 0x00, 0x01, 'a', 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name:  "length beyond the input",
+			input: []byte{0x0c, 0x00, 0x01, 'a', 0x7f, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+		},
+		{
+			name:  "bytes beyond the input",
+			input: []byte{0x07, 0x00, 0x01, 'a', 0x7f, 0xff, 0xff, 0xff, 0x01, 0x02},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewDecoder(bytes.NewReader(tt.input)).Token(); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
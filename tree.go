@@ -0,0 +1,144 @@
+package nbt
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// toTree converts any value Marshal accepts into the generic representation
+// Unmarshal produces for an interface{}: maps for compounds, []interface{} for lists
+// and plain Go values for everything else. It returns an error for values Marshal
+// cannot encode, such as an int, a nil interface or a list of mixed types.
+func toTree(value interface{}) (interface{}, error) {
+	if err := checkEncodable(reflect.ValueOf(value)); err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case byte, int16, int32, int64, float32, float64, string, []byte, []int32, []int64,
+		map[string]interface{}, []interface{}:
+		return value, nil
+	case bool:
+		if v {
+			return byte(1), nil
+		}
+		return byte(0), nil
+	}
+
+	var tree interface{}
+	data := Marshal("", value)
+	if _, err := Unmarshal(data, reflect.ValueOf(&tree).Elem()); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// checkEncodable returns an error if Marshal cannot encode v. It walks v the way the
+// writer does, so struct fields the writer leaves out are not checked.
+func checkEncodable(v reflect.Value) error {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return errors.New("nbt: cannot encode nil")
+	}
+
+	switch typeOf(v.Type()) {
+	case TagNone:
+		return fmt.Errorf("nbt: cannot encode %v", v.Type())
+	case TagList:
+		var first TagType
+		for i := 0; i < v.Len(); i++ {
+			e := v.Index(i)
+			if e.Kind() == reflect.Interface {
+				e = e.Elem()
+			}
+			if err := checkEncodable(e); err != nil {
+				return err
+			}
+			if i == 0 {
+				first = typeOf(e.Type())
+			} else if t := typeOf(e.Type()); t != first {
+				return fmt.Errorf("nbt: cannot encode a list of %v and %v", first, t)
+			}
+		}
+	case TagCompound:
+		if v.Kind() == reflect.Map {
+			if v.Type().Key().Kind() != reflect.String {
+				return fmt.Errorf("nbt: cannot encode %v, map keys must be strings", v.Type())
+			}
+			iter := v.MapRange()
+			for iter.Next() {
+				if err := checkEncodable(iter.Value()); err != nil {
+					return fmt.Errorf("%w in entry %q", err, iter.Key().String())
+				}
+			}
+			return nil
+		}
+
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name, omitEmpty := parseFieldTag(f)
+			if f.PkgPath != "" || name == "-" || omitEmpty && isEmptyValue(v.Field(i)) {
+				continue
+			}
+			if kind := f.Type.Kind(); (kind == reflect.Interface || kind == reflect.Ptr) && v.Field(i).IsNil() {
+				continue
+			}
+			if optional := f.Tag.Get("optional"); optional != "" && !v.FieldByName(optional).Bool() {
+				continue
+			}
+			if err := checkEncodable(v.Field(i)); err != nil {
+				return fmt.Errorf("%w in field %s", err, f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// treeOf is toTree for callers that cannot fail. Values Marshal cannot encode are
+// returned as they are, to be compared or formatted without conversion.
+func treeOf(value interface{}) interface{} {
+	if tree, err := toTree(value); err == nil {
+		return tree
+	}
+	return value
+}
+
+// copyTree returns a deep copy of a generic tree, so it can be inserted at several
+// places without aliasing.
+func copyTree(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			m[key] = copyTree(nested)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, nested := range v {
+			list[i] = copyTree(nested)
+		}
+		return list
+	case []byte:
+		return append([]byte{}, v...)
+	case []int32:
+		return append([]int32{}, v...)
+	case []int64:
+		return append([]int64{}, v...)
+	}
+	return value
+}
+
+// tagTypeOf returns the tag type a generic tree value is encoded as.
+func tagTypeOf(value interface{}) TagType {
+	if value == nil {
+		return TagEnd
+	}
+	return typeOf(reflect.TypeOf(value))
+}
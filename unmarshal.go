@@ -6,59 +6,74 @@ import (
 )
 
 func Unmarshal(data []byte, value reflect.Value) (tagName string, err error) {
-	buf := bytes.NewBuffer(data)
-	tagType, err := readTagType(buf)
+	r := bytes.NewBuffer(data)
+	tagType, err := readTagType(r)
 	if err != nil {
 		return
 	}
 
-	if tagType == tagEnd {
+	if tagType == TagEnd {
 		return
 	}
 
-	tagName, err = readString(buf)
+	tagName, err = readString(r)
 	if err != nil {
 		return
 	}
 
-	err = readValue(buf, tagType, value)
+	err = readValue(r, tagType, value)
 	if err != nil {
 		return
 	}
 	return
 }
 
-func readValue(buf *bytes.Buffer, tagType namedTagType, v reflect.Value) error {
+func readValue(r reader, tagType TagType, v reflect.Value) error {
+	// Pointers are allocated as needed and decoded into
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
 	switch tagType {
-	case tagByte:
-		return readTagByte(buf, v)
-	case tagShort:
-		return readTagShort(buf, v)
-	case tagInt:
-		return readTagInt(buf, v)
-	case tagLong:
-		return readTagLong(buf, v)
-	case tagFloat:
-		return readTagFloat(buf, v)
-	case tagDouble:
-		return readTagDouble(buf, v)
-	case tagString:
-		return readTagString(buf, v)
-	case tagList:
-		return readTagList(buf, v)
-	case tagCompound:
+	case TagByte:
+		return readTagByte(r, v)
+	case TagShort:
+		return readTagShort(r, v)
+	case TagInt:
+		return readTagInt(r, v)
+	case TagLong:
+		return readTagLong(r, v)
+	case TagFloat:
+		return readTagFloat(r, v)
+	case TagDouble:
+		return readTagDouble(r, v)
+	case TagString:
+		return readTagString(r, v)
+	case TagList:
+		return readTagList(r, v)
+	case TagCompound:
 		switch v.Kind() {
 		case reflect.Struct:
-			return readTagCompoundStruct(buf, v)
+			return readTagCompoundStruct(r, v)
 		case reflect.Map:
-			return readTagCompoundMap(buf, v)
+			return readTagCompoundMap(r, v)
+		case reflect.Interface:
+			m := reflect.ValueOf(make(map[string]interface{}))
+			if err := readTagCompoundMap(r, m); err != nil {
+				return err
+			}
+			v.Set(m)
+			return nil
 		}
-	case tagByteArray:
-		return readTagByteArray(buf, v)
-	case tagIntArray:
-		return readTagIntArray(buf, v)
-	case tagLongArray:
-		return readTagLongArray(buf, v)
+	case TagByteArray:
+		return readTagByteArray(r, v)
+	case TagIntArray:
+		return readTagIntArray(r, v)
+	case TagLongArray:
+		return readTagLongArray(r, v)
 	}
 	return nil
 }
@@ -21,6 +21,29 @@ func TestUnmarshalCompoundMap(t *testing.T) {
 				"StringTag": "hello, world",
 			},
 		},
+		{
+			name:  "bigtest",
+			input: test.BigTestBytes,
+			expected: map[string]interface{}{
+				"longTest":   int64(9223372036854775807),
+				"shortTest":  int16(32767),
+				"stringTest": "HELLO WORLD THIS IS A TEST STRING \xc3\x85\xc3\x84\xc3\x96!",
+				"floatTest":  float32(0.49823147058486938),
+				"intTest":    int32(2147483647),
+				"nested compound test": map[string]interface{}{
+					"ham": map[string]interface{}{"name": "Hampus", "value": float32(0.75)},
+					"egg": map[string]interface{}{"name": "Eggbert", "value": float32(0.5)},
+				},
+				"listTest (long)": []interface{}{int64(11), int64(12), int64(13), int64(14), int64(15)},
+				"listTest (compound)": []interface{}{
+					map[string]interface{}{"name": "Compound tag #0", "created-on": int64(1264099775885)},
+					map[string]interface{}{"name": "Compound tag #1", "created-on": int64(1264099775885)},
+				},
+				"byteTest": byte(127),
+				"byteArrayTest (the first 1000 values of (n*n*255+n*7)%100, starting with n=0 (0, 62, 34, 16, 8, ...))": test.BigTestByteArray(),
+				"doubleTest": 0.49312871321823148,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -67,6 +90,16 @@ func TestUnmarshalCompoundStruct(t *testing.T) {
 			want:        test.BananramaStruct,
 			wantErr:     false,
 		},
+		{
+			name:        "unknown tags are skipped",
+			tagBytes:    test.BigTestBytes,
+			wantTagName: "Level",
+			want: test.BigTestPartial{
+				IntTest:    2147483647,
+				DoubleTest: 0.49312871321823148,
+			},
+			wantErr: false,
+		},
 		{
 			name:        "bigtest",
 			tagBytes:    test.BigTestBytes,
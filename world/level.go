@@ -0,0 +1,164 @@
+package world
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// LevelData is the content of level.dat.
+//
+// Only the common keys have fields. Keys without one are kept when the level is loaded
+// with LoadLevel and written back by Save, except inside lists and maps such as
+// GameRules, which are replaced as a whole. Fields whose key the loaded file lacks,
+// such as the ones of later versions, are only written once set to a non-zero value.
+type LevelData struct {
+	Data Level `nbt:"Data"`
+
+	// raw holds the loaded file, including the keys without a field.
+	raw map[string]interface{}
+}
+
+// Level is the Data compound of level.dat.
+type Level struct {
+	DataVersion int32   `nbt:"DataVersion"`
+	Version     Version `nbt:"Version"`
+	NBTVersion  int32   `nbt:"version"`
+
+	LevelName  string   `nbt:"LevelName"`
+	LastPlayed int64    `nbt:"LastPlayed"`
+	WasModded  bool     `nbt:"WasModded"`
+	Brands     []string `nbt:"ServerBrands"`
+
+	GameType         int32 `nbt:"GameType"`
+	Difficulty       byte  `nbt:"Difficulty"`
+	DifficultyLocked bool  `nbt:"DifficultyLocked"`
+	Hardcore         bool  `nbt:"hardcore"`
+	AllowCommands    bool  `nbt:"allowCommands"`
+	Initialized      bool  `nbt:"initialized"`
+
+	Time    int64 `nbt:"Time"`
+	DayTime int64 `nbt:"DayTime"`
+
+	SpawnX     int32   `nbt:"SpawnX"`
+	SpawnY     int32   `nbt:"SpawnY"`
+	SpawnZ     int32   `nbt:"SpawnZ"`
+	SpawnAngle float32 `nbt:"SpawnAngle"`
+
+	Raining          bool  `nbt:"raining"`
+	RainTime         int32 `nbt:"rainTime"`
+	Thundering       bool  `nbt:"thundering"`
+	ThunderTime      int32 `nbt:"thunderTime"`
+	ClearWeatherTime int32 `nbt:"clearWeatherTime"`
+
+	BorderCenterX        float64 `nbt:"BorderCenterX"`
+	BorderCenterZ        float64 `nbt:"BorderCenterZ"`
+	BorderSize           float64 `nbt:"BorderSize"`
+	BorderSafeZone       float64 `nbt:"BorderSafeZone"`
+	BorderDamagePerBlock float64 `nbt:"BorderDamagePerBlock"`
+	BorderWarningBlocks  float64 `nbt:"BorderWarningBlocks"`
+	BorderWarningTime    float64 `nbt:"BorderWarningTime"`
+	BorderSizeLerpTarget float64 `nbt:"BorderSizeLerpTarget"`
+	BorderSizeLerpTime   int64   `nbt:"BorderSizeLerpTime"`
+
+	WorldGenSettings WorldGenSettings  `nbt:"WorldGenSettings"`
+	GameRules        map[string]string `nbt:"GameRules"`
+	DataPacks        DataPacks         `nbt:"DataPacks"`
+
+	// DragonFight is only written if HasDragonFight is set, and Player if HasPlayer is.
+	// Both are set when loading a file that holds them.
+	HasDragonFight bool        `nbt:"-"`
+	DragonFight    DragonFight `nbt:"DragonFight" optional:"HasDragonFight"`
+	HasPlayer      bool        `nbt:"-"`
+	Player         Player      `nbt:"Player" optional:"HasPlayer"`
+}
+
+// LastPlayedTime returns the time the world was last saved.
+func (l *Level) LastPlayedTime() time.Time {
+	return time.Unix(0, l.LastPlayed*int64(time.Millisecond))
+}
+
+// Version identifies the game version that last saved the world.
+type Version struct {
+	ID       int32  `nbt:"Id"`
+	Name     string `nbt:"Name"`
+	Series   string `nbt:"Series"`
+	Snapshot bool   `nbt:"Snapshot"`
+}
+
+type WorldGenSettings struct {
+	Seed             int64 `nbt:"seed"`
+	GenerateFeatures bool  `nbt:"generate_features"`
+	BonusChest       bool  `nbt:"bonus_chest"`
+
+	// Dimensions holds the generator of every dimension, by dimension ID.
+	Dimensions map[string]interface{} `nbt:"dimensions"`
+}
+
+type DataPacks struct {
+	Enabled  []string `nbt:"Enabled"`
+	Disabled []string `nbt:"Disabled"`
+}
+
+type DragonFight struct {
+	DragonKilled       bool    `nbt:"DragonKilled"`
+	PreviouslyKilled   bool    `nbt:"PreviouslyKilled"`
+	NeedsStateScanning bool    `nbt:"NeedsStateScanning"`
+	Dragon             []int32 `nbt:"Dragon,omitempty"`
+
+	// Gateways lists the angles of the end gateways still to be generated.
+	Gateways []int32 `nbt:"Gateways" nbt_type:"list"`
+
+	// ExitPortalLocation is a compound of X, Y and Z before 1.20.5, and an int array
+	// since.
+	ExitPortalLocation interface{} `nbt:"ExitPortalLocation,omitempty"`
+}
+
+// LoadLevel reads a level.dat file, compressed with gzip as vanilla writes it or not
+// compressed at all. The fields follow the format of 1.16 and later.
+func LoadLevel(name string) (*LevelData, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := ReadLevel(f)
+	if err != nil {
+		return nil, fmt.Errorf("world: %s: %w", name, err)
+	}
+	return l, nil
+}
+
+// ReadLevel reads the content of a level.dat file from r.
+func ReadLevel(r io.Reader) (*LevelData, error) {
+	l := &LevelData{}
+	raw, err := readTyped(r, l)
+	if err != nil {
+		return nil, err
+	}
+	l.raw = raw
+	return l, nil
+}
+
+// Write writes the level to w, compressed with gzip, after setting LastPlayed to the
+// current time as the game does when saving.
+func (l *LevelData) Write(w io.Writer) error {
+	l.Data.LastPlayed = time.Now().UnixNano() / int64(time.Millisecond)
+	if l.raw == nil {
+		l.raw = make(map[string]interface{})
+	}
+	return writeTyped(w, l.raw, *l)
+}
+
+// Save writes the level to the file name. The new content goes to a temporary file
+// renamed over name, and the previous file is kept as name_old like the game does.
+func (l *LevelData) Save(name string) error {
+	buf := &bytes.Buffer{}
+	if err := l.Write(buf); err != nil {
+		return err
+	}
+	return saveFile(name, buf.Bytes())
+}
@@ -0,0 +1,254 @@
+package world
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const levelSNBT = `{Data:{
+	DataVersion:3465,version:19133,Version:{Id:3465,Name:"1.20.1",Series:"main",Snapshot:0b},
+	LevelName:"Test World",LastPlayed:1700000000000L,WasModded:0b,ServerBrands:["vanilla"],
+	GameType:0,Difficulty:2b,DifficultyLocked:0b,hardcore:0b,allowCommands:1b,initialized:1b,
+	Time:123456L,DayTime:6000L,SpawnX:16,SpawnY:64,SpawnZ:-32,SpawnAngle:0.0f,
+	raining:0b,rainTime:1000,thundering:0b,thunderTime:2000,clearWeatherTime:0,
+	BorderCenterX:0.0d,BorderCenterZ:0.0d,BorderSize:5.9999968E7d,BorderSafeZone:5.0d,
+	BorderDamagePerBlock:0.2d,BorderWarningBlocks:5.0d,BorderWarningTime:15.0d,
+	BorderSizeLerpTarget:5.9999968E7d,BorderSizeLerpTime:0L,
+	WorldGenSettings:{seed:-4172144997902289642L,generate_features:1b,bonus_chest:0b,
+		dimensions:{"minecraft:overworld":{type:"minecraft:overworld",generator:{type:"minecraft:noise"}}}},
+	GameRules:{doDaylightCycle:"true",keepInventory:"false"},
+	DataPacks:{Enabled:["vanilla"],Disabled:["bundle"]},
+	DragonFight:{DragonKilled:1b,PreviouslyKilled:1b,NeedsStateScanning:0b,Gateways:[1,5,12],
+		Dragon:[I;1,2,3,4],ExitPortalLocation:{X:0,Y:64,Z:0}},
+	Player:` + playerSNBT + `,
+	CustomBossEvents:{},ScheduledEvents:[],WanderingTraderSpawnChance:25
+}}`
+
+func writeLevel(t *testing.T, snbt string) string {
+	t.Helper()
+
+	tree, err := nbt.ParseSNBT(snbt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "level.dat")
+	writeNBT(t, name, tree)
+	return name
+}
+
+func readTree(t *testing.T, name string) interface{} {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, compression, err := nbt.Decompress(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compression != nbt.CompressionGzip {
+		t.Errorf("%s is compressed with %v, want gzip", name, compression)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.ReadFrom(r)
+	var tree interface{}
+	if _, err = nbt.Unmarshal(buf.Bytes(), reflect.ValueOf(&tree).Elem()); err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestLoadLevel(t *testing.T) {
+	l, err := LoadLevel(writeLevel(t, levelSNBT))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := l.Data
+	if d.LevelName != "Test World" || d.Version.Name != "1.20.1" || d.DataVersion != 3465 {
+		t.Errorf("got level %q version %q %d", d.LevelName, d.Version.Name, d.DataVersion)
+	}
+	if d.WorldGenSettings.Seed != -4172144997902289642 || !d.WorldGenSettings.GenerateFeatures {
+		t.Errorf("WorldGenSettings = %+v", d.WorldGenSettings)
+	}
+	if d.GameRules["doDaylightCycle"] != "true" {
+		t.Errorf("GameRules = %v", d.GameRules)
+	}
+	if !reflect.DeepEqual(d.DataPacks, DataPacks{Enabled: []string{"vanilla"}, Disabled: []string{"bundle"}}) {
+		t.Errorf("DataPacks = %+v", d.DataPacks)
+	}
+	if !d.HasDragonFight || !d.DragonFight.DragonKilled || !reflect.DeepEqual(d.DragonFight.Gateways, []int32{1, 5, 12}) {
+		t.Errorf("DragonFight = %+v", d.DragonFight)
+	}
+	if !d.HasPlayer || d.Player.XpLevel != 3 || !reflect.DeepEqual(d.Player.Pos, []float64{0.5, 64, 0.5}) {
+		t.Errorf("Player = %+v", d.Player)
+	}
+	if want := time.Unix(1700000000, 0); !d.LastPlayedTime().Equal(want) {
+		t.Errorf("LastPlayedTime() = %v, want %v", d.LastPlayedTime(), want)
+	}
+}
+
+func TestSaveLevel(t *testing.T) {
+	name := writeLevel(t, levelSNBT)
+	original, _ := os.ReadFile(name)
+	before := readTree(t, name)
+
+	l, err := LoadLevel(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Data.LevelName = "Renamed"
+	l.Data.GameRules["keepInventory"] = "true"
+	l.Data.Player.XpLevel = 30
+
+	start := time.Now().Add(-time.Second)
+	if err = l.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	if l.Data.LastPlayedTime().Before(start) || l.Data.LastPlayedTime().After(time.Now()) {
+		t.Errorf("LastPlayed was set to %v", l.Data.LastPlayedTime())
+	}
+
+	if old, _ := os.ReadFile(name + "_old"); !bytes.Equal(old, original) {
+		t.Errorf("level.dat_old does not hold the previous file")
+	}
+
+	// Unknown keys and tag types are preserved, so only the changes show up.
+	got := nbt.FormatDiff(nbt.Diff(before, readTree(t, name), nbt.DiffOptions{}))
+	want := "- Data.GameRules.keepInventory: \"false\"\n+ Data.GameRules.keepInventory: \"true\"\n" +
+		"- Data.LastPlayed: 1700000000000L\n+ Data.LastPlayed: "
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("diff after saving starts with:\n%s\nwant\n%s", got, want)
+	}
+	if want = "- Data.LevelName: \"Test World\"\n+ Data.LevelName: \"Renamed\"\n" +
+		"- Data.Player.XpLevel: 3\n+ Data.Player.XpLevel: 30\n"; !strings.HasSuffix(got, want) {
+		t.Errorf("diff after saving ends with:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSaveLevelClearedFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		clear func(d *Level)
+		path  string
+	}{
+		{name: "omitempty slice", clear: func(d *Level) { d.DragonFight.Dragon = nil }, path: "Data.DragonFight.Dragon"},
+		{name: "omitempty interface", clear: func(d *Level) { d.DragonFight.ExitPortalLocation = nil }, path: "Data.DragonFight.ExitPortalLocation"},
+		{name: "map entry", clear: func(d *Level) { delete(d.GameRules, "keepInventory") }, path: "Data.GameRules.keepInventory"},
+		{
+			name:  "nested map entry",
+			clear: func(d *Level) { delete(d.WorldGenSettings.Dimensions, "minecraft:overworld") },
+			path:  `Data.WorldGenSettings.dimensions."minecraft:overworld"`,
+		},
+		{name: "optional compound", clear: func(d *Level) { d.HasDragonFight = false }, path: "Data.DragonFight"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := writeLevel(t, levelSNBT)
+			l, err := LoadLevel(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.clear(&l.Data)
+			if err = l.Save(name); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := nbt.MustParsePath(tt.path).Count(readTree(t, name)); n != 0 {
+				t.Errorf("%s still saved", tt.path)
+			}
+			if l, err = LoadLevel(name); err != nil {
+				t.Fatal(err)
+			}
+			if err = l.Save(name); err != nil {
+				t.Fatal(err)
+			}
+			if n := nbt.MustParsePath(tt.path).Count(readTree(t, name)); n != 0 {
+				t.Errorf("%s saved again after reloading", tt.path)
+			}
+		})
+	}
+}
+
+func TestSaveMinimalLevel(t *testing.T) {
+	name := writeLevel(t, `{Data:{LevelName:"Old",RandomSeed:42L,LastPlayed:1L,version:19132,GameType:1}}`)
+	before := readTree(t, name)
+
+	l, err := LoadLevel(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only LastPlayed changes: none of the keys of later versions are added.
+	for _, c := range nbt.Diff(before, readTree(t, name), nbt.DiffOptions{}) {
+		if c.Path.String() != "Data.LastPlayed" {
+			t.Errorf("saving changed %s", c)
+		}
+	}
+
+	l.Data.BorderSize = 1000
+	l.Data.WorldGenSettings.Seed = 42
+	if err = l.Save(name); err != nil {
+		t.Fatal(err)
+	}
+	got := nbt.FormatDiff(nbt.Diff(before, readTree(t, name), nbt.DiffOptions{}))
+	want := "+ Data.BorderSize: 1000.0d\n- Data.LastPlayed: 1L\n+ Data.LastPlayed: " + nbt.FormatSNBT(l.Data.LastPlayed) +
+		"\n+ Data.WorldGenSettings: {seed:42L}\n"
+	if got != want {
+		t.Errorf("diff after setting fields:\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSaveServerLevel(t *testing.T) {
+	name := writeLevel(t, `{Data:{LevelName:"Server",GameRules:{},DataPacks:{Enabled:[],Disabled:[]}}}`)
+	l, err := LoadLevel(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Data.HasPlayer || l.Data.HasDragonFight {
+		t.Errorf("HasPlayer = %v, HasDragonFight = %v, want false", l.Data.HasPlayer, l.Data.HasDragonFight)
+	}
+	if err = l.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	data := readTree(t, name).(map[string]interface{})["Data"].(map[string]interface{})
+	for _, key := range []string{"Player", "DragonFight"} {
+		if _, ok := data[key]; ok {
+			t.Errorf("saving added %s", key)
+		}
+	}
+}
+
+func TestWorldSaveLevel(t *testing.T) {
+	dir := testWorld(t)
+	w, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Level.Data.LevelName = "Saved"
+	if err = w.SaveLevel(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := LoadLevel(filepath.Join(dir, "level.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Data.LevelName != "Saved" {
+		t.Errorf("LevelName = %q after saving, want %q", l.Data.LevelName, "Saved")
+	}
+}
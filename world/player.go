@@ -0,0 +1,268 @@
+package world
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Player is the data of a player, stored in playerdata/<uuid>.dat and, for the player
+// of a singleplayer world, in level.dat. The fields follow the format of 1.20.
+type Player struct {
+	DataVersion int32 `nbt:"DataVersion"`
+
+	// UUID is the UUID of the player as four ints, see UUIDFromInts.
+	UUID []int32 `nbt:"UUID"`
+
+	Dimension    string    `nbt:"Dimension"`
+	Pos          []float64 `nbt:"Pos"`
+	Motion       []float64 `nbt:"Motion"`
+	Rotation     []float32 `nbt:"Rotation"`
+	OnGround     bool      `nbt:"OnGround"`
+	FallDistance float32   `nbt:"FallDistance"`
+	Fire         int16     `nbt:"Fire"`
+	Air          int16     `nbt:"Air"`
+	Invulnerable bool      `nbt:"Invulnerable"`
+
+	GameType         int32 `nbt:"playerGameType"`
+	PreviousGameType int32 `nbt:"previousPlayerGameType"`
+
+	Health           float32 `nbt:"Health"`
+	AbsorptionAmount float32 `nbt:"AbsorptionAmount"`
+	HurtTime         int16   `nbt:"HurtTime"`
+	DeathTime        int16   `nbt:"DeathTime"`
+
+	FoodLevel           int32   `nbt:"foodLevel"`
+	FoodSaturationLevel float32 `nbt:"foodSaturationLevel"`
+	FoodExhaustionLevel float32 `nbt:"foodExhaustionLevel"`
+	FoodTickTimer       int32   `nbt:"foodTickTimer"`
+
+	XpLevel int32   `nbt:"XpLevel"`
+	XpP     float32 `nbt:"XpP"`
+	XpTotal int32   `nbt:"XpTotal"`
+	XpSeed  int32   `nbt:"XpSeed"`
+	Score   int32   `nbt:"Score"`
+
+	Inventory        []ItemStack `nbt:"Inventory"`
+	EnderItems       []ItemStack `nbt:"EnderItems"`
+	SelectedItemSlot int32       `nbt:"SelectedItemSlot"`
+
+	Abilities     Abilities   `nbt:"abilities"`
+	Attributes    []Attribute `nbt:"Attributes"`
+	ActiveEffects []Effect    `nbt:"ActiveEffects,omitempty"`
+	RecipeBook    RecipeBook  `nbt:"recipeBook"`
+
+	// The spawn point is only written if HasSpawn is set, which happens when loading a
+	// player that has one.
+	HasSpawn       bool    `nbt:"-"`
+	SpawnX         int32   `nbt:"SpawnX" optional:"HasSpawn"`
+	SpawnY         int32   `nbt:"SpawnY" optional:"HasSpawn"`
+	SpawnZ         int32   `nbt:"SpawnZ" optional:"HasSpawn"`
+	SpawnAngle     float32 `nbt:"SpawnAngle" optional:"HasSpawn"`
+	SpawnDimension string  `nbt:"SpawnDimension" optional:"HasSpawn"`
+	SpawnForced    bool    `nbt:"SpawnForced" optional:"HasSpawn"`
+}
+
+// Inventory slots beyond the 36 slots of the main inventory and hotbar. Slot tags are
+// signed bytes, so the offhand slot -106 reads as 150.
+const (
+	SlotFeet    byte = 100
+	SlotLegs    byte = 101
+	SlotChest   byte = 102
+	SlotHead    byte = 103
+	SlotOffhand byte = 256 - 106
+)
+
+// ItemStack is a stack of items in an inventory.
+type ItemStack struct {
+	Slot  byte   `nbt:"Slot"`
+	ID    string `nbt:"id"`
+	Count byte   `nbt:"Count"`
+
+	// Tag holds the item data, such as enchantments and the display name.
+	Tag map[string]interface{} `nbt:"tag,omitempty"`
+
+	// Unknown holds the keys of the item without a field, written back by Save.
+	Unknown map[string]interface{} `nbt:"-" unknown:""`
+}
+
+type Abilities struct {
+	Flying       bool    `nbt:"flying"`
+	FlySpeed     float32 `nbt:"flySpeed"`
+	WalkSpeed    float32 `nbt:"walkSpeed"`
+	Instabuild   bool    `nbt:"instabuild"`
+	Invulnerable bool    `nbt:"invulnerable"`
+	MayBuild     bool    `nbt:"mayBuild"`
+	MayFly       bool    `nbt:"mayfly"`
+}
+
+type Attribute struct {
+	Name      string              `nbt:"Name"`
+	Base      float64             `nbt:"Base"`
+	Modifiers []AttributeModifier `nbt:"Modifiers,omitempty"`
+
+	// Unknown holds the keys of the attribute without a field, written back by Save.
+	Unknown map[string]interface{} `nbt:"-" unknown:""`
+}
+
+type AttributeModifier struct {
+	Name      string  `nbt:"Name"`
+	Amount    float64 `nbt:"Amount"`
+	Operation int32   `nbt:"Operation"`
+	UUID      []int32 `nbt:"UUID"`
+
+	// Unknown holds the keys of the modifier without a field, written back by Save.
+	Unknown map[string]interface{} `nbt:"-" unknown:""`
+}
+
+// Effect is an active status effect.
+type Effect struct {
+	ID            int32 `nbt:"Id"`
+	Amplifier     byte  `nbt:"Amplifier"`
+	Duration      int32 `nbt:"Duration"`
+	Ambient       bool  `nbt:"Ambient"`
+	ShowParticles bool  `nbt:"ShowParticles"`
+	ShowIcon      bool  `nbt:"ShowIcon"`
+
+	// Unknown holds the keys of the effect without a field, such as
+	// FactorCalculationData, written back by Save.
+	Unknown map[string]interface{} `nbt:"-" unknown:""`
+}
+
+type RecipeBook struct {
+	Recipes       []string `nbt:"recipes"`
+	ToBeDisplayed []string `nbt:"toBeDisplayed"`
+
+	IsGuiOpen                           bool `nbt:"isGuiOpen"`
+	IsFilteringCraftable                bool `nbt:"isFilteringCraftable"`
+	IsFurnaceGuiOpen                    bool `nbt:"isFurnaceGuiOpen"`
+	IsFurnaceFilteringCraftable         bool `nbt:"isFurnaceFilteringCraftable"`
+	IsBlastingFurnaceGuiOpen            bool `nbt:"isBlastingFurnaceGuiOpen"`
+	IsBlastingFurnaceFilteringCraftable bool `nbt:"isBlastingFurnaceFilteringCraftable"`
+	IsSmokerGuiOpen                     bool `nbt:"isSmokerGuiOpen"`
+	IsSmokerFilteringCraftable          bool `nbt:"isSmokerFilteringCraftable"`
+}
+
+// PlayerData is the content of a playerdata/<uuid>.dat file.
+//
+// Keys without a field are kept when the file is loaded with LoadPlayer and written
+// back by Save. Items, attributes and effects keep theirs in their Unknown field, so
+// they stay with the element when lists are edited. Maps are replaced as a whole.
+type PlayerData struct {
+	Player
+
+	// raw holds the loaded file, including the keys without a field.
+	raw map[string]interface{}
+}
+
+// LoadPlayer reads a player data file, compressed with gzip as vanilla writes it or not
+// compressed at all.
+func LoadPlayer(name string) (*PlayerData, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p, err := ReadPlayer(f)
+	if err != nil {
+		return nil, fmt.Errorf("world: %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// ReadPlayer reads the content of a player data file from r.
+func ReadPlayer(r io.Reader) (*PlayerData, error) {
+	p := &PlayerData{}
+	raw, err := readTyped(r, &p.Player)
+	if err != nil {
+		return nil, err
+	}
+	p.raw = raw
+	return p, nil
+}
+
+// Write writes the player to w, compressed with gzip.
+func (p *PlayerData) Write(w io.Writer) error {
+	if p.raw == nil {
+		p.raw = make(map[string]interface{})
+	}
+	return writeTyped(w, p.raw, p.Player)
+}
+
+// Save writes the player to the file name. The new content goes to a temporary file
+// renamed over name, and the previous file is kept as name_old like the game does.
+func (p *PlayerData) Save(name string) error {
+	buf := &bytes.Buffer{}
+	if err := p.Write(buf); err != nil {
+		return err
+	}
+	return saveFile(name, buf.Bytes())
+}
+
+// PlayerFile returns the name of the data file of a player.
+func (w *World) PlayerFile(uuid [16]byte) string {
+	return filepath.Join(w.Dir, "playerdata", FormatUUID(uuid)+".dat")
+}
+
+// LoadPlayer reads the data file of a player.
+func (w *World) LoadPlayer(uuid [16]byte) (*PlayerData, error) {
+	return LoadPlayer(w.PlayerFile(uuid))
+}
+
+// UUIDFromInts converts a UUID stored as four ints, most significant first, as
+// entities and players have since 1.16.
+func UUIDFromInts(ints []int32) ([16]byte, error) {
+	var uuid [16]byte
+	if len(ints) != 4 {
+		return uuid, fmt.Errorf("world: UUID has %d ints, want 4", len(ints))
+	}
+	for i, n := range ints {
+		uuid[i*4] = byte(n >> 24)
+		uuid[i*4+1] = byte(n >> 16)
+		uuid[i*4+2] = byte(n >> 8)
+		uuid[i*4+3] = byte(n)
+	}
+	return uuid, nil
+}
+
+// UUIDToInts converts a UUID to the four ints it is stored as.
+func UUIDToInts(uuid [16]byte) []int32 {
+	ints := make([]int32, 4)
+	for i := range ints {
+		ints[i] = int32(uint32(uuid[i*4])<<24 | uint32(uuid[i*4+1])<<16 | uint32(uuid[i*4+2])<<8 | uint32(uuid[i*4+3]))
+	}
+	return ints
+}
+
+// FormatUUID formats a UUID in its canonical form, such as
+// 069a79f4-44e9-4726-a5be-fca90e38aaf5.
+func FormatUUID(uuid [16]byte) string {
+	s := hex.EncodeToString(uuid[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// ParseUUID parses a UUID in its canonical form, with or without dashes.
+func ParseUUID(s string) ([16]byte, error) {
+	var uuid [16]byte
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != len(uuid) {
+		return uuid, fmt.Errorf("world: invalid UUID %q", s)
+	}
+	copy(uuid[:], b)
+	return uuid, nil
+}
+
+// ID returns the UUID of the player.
+func (p *Player) ID() ([16]byte, error) {
+	return UUIDFromInts(p.UUID)
+}
+
+// SetID sets the UUID of the player.
+func (p *Player) SetID(uuid [16]byte) {
+	p.UUID = UUIDToInts(uuid)
+}
@@ -0,0 +1,248 @@
+package world
+
+import (
+	"github.com/junglemc/nbt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const playerSNBT = `{
+	DataVersion:3465,UUID:[I;110787060,1156138790,-1514210135,238594805],
+	Dimension:"minecraft:overworld",Pos:[0.5d,64.0d,0.5d],Motion:[0.0d,-0.0784d,0.0d],Rotation:[90.0f,0.0f],
+	OnGround:1b,FallDistance:0.0f,Fire:-20s,Air:300s,Invulnerable:0b,PortalCooldown:0,
+	playerGameType:0,previousPlayerGameType:-1,
+	Health:20.0f,AbsorptionAmount:0.0f,HurtTime:0s,DeathTime:0s,HurtByTimestamp:0,
+	foodLevel:20,foodSaturationLevel:5.0f,foodExhaustionLevel:0.0f,foodTickTimer:0,
+	XpLevel:3,XpP:0.5f,XpTotal:30,XpSeed:-1210986478,Score:30,
+	Inventory:[
+		{Slot:0b,id:"minecraft:diamond_sword",Count:1b,tag:{Damage:0,Enchantments:[{id:"minecraft:sharpness",lvl:5s}]},FutureKey:"kept"},
+		{Slot:1b,id:"minecraft:stone",Count:64b},
+		{Slot:-106b,id:"minecraft:shield",Count:1b,tag:{Damage:3}}
+	],
+	EnderItems:[{Slot:0b,id:"minecraft:elytra",Count:1b}],
+	SelectedItemSlot:0,
+	abilities:{flying:0b,flySpeed:0.05f,walkSpeed:0.1f,instabuild:0b,invulnerable:0b,mayBuild:1b,mayfly:0b},
+	Attributes:[
+		{Name:"minecraft:generic.max_health",Base:20.0d},
+		{Name:"minecraft:generic.movement_speed",Base:0.1d,Modifiers:[
+			{Name:"Sprinting speed boost",Amount:0.3d,Operation:2,UUID:[I;1,2,3,4],Slot:"mainhand"}
+		]}
+	],
+	ActiveEffects:[{Id:1,Amplifier:1b,Duration:600,Ambient:0b,ShowParticles:1b,ShowIcon:1b,
+		FactorCalculationData:{padding_duration:22,factor_start:0.0f,factor_target:1.0f,ticks_active:0}}],
+	recipeBook:{recipes:["minecraft:crafting_table"],toBeDisplayed:[],
+		isGuiOpen:0b,isFilteringCraftable:0b,isFurnaceGuiOpen:0b,isFurnaceFilteringCraftable:0b,
+		isBlastingFurnaceGuiOpen:0b,isBlastingFurnaceFilteringCraftable:0b,
+		isSmokerGuiOpen:0b,isSmokerFilteringCraftable:0b},
+	SpawnX:100,SpawnY:70,SpawnZ:-100,SpawnAngle:0.0f,SpawnDimension:"minecraft:overworld",SpawnForced:0b,
+	warden_spawn_tracker:{warning_level:0,ticks_since_last_warning:0,cooldown_ticks:0},
+	seenCredits:0b
+}`
+
+var notchUUID = [16]byte{0x06, 0x9a, 0x79, 0xf4, 0x44, 0xe9, 0x47, 0x26, 0xa5, 0xbe, 0xfc, 0xa9, 0x0e, 0x38, 0xaa, 0xf5}
+
+func writePlayer(t *testing.T, dir string, snbt string) string {
+	t.Helper()
+
+	tree, err := nbt.ParseSNBT(snbt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "playerdata", FormatUUID(notchUUID)+".dat")
+	writeNBT(t, name, tree)
+	return name
+}
+
+func TestLoadPlayer(t *testing.T) {
+	p, err := LoadPlayer(writePlayer(t, t.TempDir(), playerSNBT))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := p.ID(); err != nil || id != notchUUID {
+		t.Errorf("ID() = %x, %v, want %x", id, err, notchUUID)
+	}
+	if !reflect.DeepEqual(p.Pos, []float64{0.5, 64, 0.5}) || !reflect.DeepEqual(p.Rotation, []float32{90, 0}) {
+		t.Errorf("Pos = %v, Rotation = %v", p.Pos, p.Rotation)
+	}
+	if len(p.Inventory) != 3 || p.Inventory[0].ID != "minecraft:diamond_sword" || p.Inventory[2].Slot != SlotOffhand {
+		t.Errorf("Inventory = %+v", p.Inventory)
+	}
+	if len(p.EnderItems) != 1 || p.EnderItems[0].ID != "minecraft:elytra" {
+		t.Errorf("EnderItems = %+v", p.EnderItems)
+	}
+	if !p.Abilities.MayBuild || p.Abilities.FlySpeed != 0.05 {
+		t.Errorf("Abilities = %+v", p.Abilities)
+	}
+	if len(p.Attributes) != 2 || len(p.Attributes[1].Modifiers) != 1 || p.Attributes[1].Modifiers[0].Amount != 0.3 {
+		t.Errorf("Attributes = %+v", p.Attributes)
+	}
+	if len(p.ActiveEffects) != 1 || p.ActiveEffects[0].ID != 1 || p.ActiveEffects[0].Duration != 600 || !p.ActiveEffects[0].ShowIcon {
+		t.Errorf("ActiveEffects = %+v", p.ActiveEffects)
+	}
+	if _, ok := p.ActiveEffects[0].Unknown["FactorCalculationData"]; !ok || len(p.ActiveEffects[0].Unknown) != 1 {
+		t.Errorf("ActiveEffects[0].Unknown = %v", p.ActiveEffects[0].Unknown)
+	}
+	if !reflect.DeepEqual(p.Inventory[0].Unknown, map[string]interface{}{"FutureKey": "kept"}) || p.Inventory[1].Unknown != nil {
+		t.Errorf("Inventory unknown keys = %v, %v", p.Inventory[0].Unknown, p.Inventory[1].Unknown)
+	}
+	if p.XpLevel != 3 || p.XpTotal != 30 {
+		t.Errorf("XpLevel = %d, XpTotal = %d", p.XpLevel, p.XpTotal)
+	}
+	if !p.HasSpawn || p.SpawnX != 100 || p.SpawnZ != -100 {
+		t.Errorf("HasSpawn = %v, spawn at %d,%d", p.HasSpawn, p.SpawnX, p.SpawnZ)
+	}
+	if !reflect.DeepEqual(p.RecipeBook.Recipes, []string{"minecraft:crafting_table"}) {
+		t.Errorf("RecipeBook = %+v", p.RecipeBook)
+	}
+}
+
+func TestSavePlayer(t *testing.T) {
+	name := writePlayer(t, t.TempDir(), playerSNBT)
+
+	p, err := LoadPlayer(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Inventory = p.Inventory[1:]
+	p.Inventory[0].Count = 32
+	if err = p.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything but the inventory, including keys without a field, is unchanged.
+	want, _ := nbt.ParseSNBT(playerSNBT)
+	inventory, _ := nbt.ParseSNBT(`[{Slot:1b,id:"minecraft:stone",Count:32b},{Slot:-106b,id:"minecraft:shield",Count:1b,tag:{Damage:3}}]`)
+	want.(map[string]interface{})["Inventory"] = inventory
+	if changes := nbt.Diff(want, readTree(t, name), nbt.DiffOptions{}); len(changes) != 0 {
+		t.Errorf("saved player differs:\n%s", nbt.FormatDiff(changes))
+	}
+}
+
+func TestSavePlayerUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(p *PlayerData)
+	}{
+		{name: "no edit", edit: func(p *PlayerData) {}},
+		{name: "reordered lists", edit: func(p *PlayerData) {
+			inventory := p.Inventory
+			p.Inventory = []ItemStack{inventory[2], inventory[1], inventory[0]}
+			p.Attributes[0], p.Attributes[1] = p.Attributes[1], p.Attributes[0]
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := writePlayer(t, t.TempDir(), playerSNBT)
+			p, err := LoadPlayer(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(p)
+			if err = p.Save(name); err != nil {
+				t.Fatal(err)
+			}
+
+			want, _ := nbt.ParseSNBT(playerSNBT)
+			if changes := nbt.Diff(want, readTree(t, name), nbt.DiffOptions{IgnoreListOrder: true}); len(changes) != 0 {
+				t.Errorf("saved player differs:\n%s", nbt.FormatDiff(changes))
+			}
+		})
+	}
+}
+
+func TestSavePlayerWithoutSpawn(t *testing.T) {
+	name := writePlayer(t, t.TempDir(), playerSNBT)
+	p, err := LoadPlayer(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.HasSpawn = false
+	if err = p.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, err = LoadPlayer(name); err != nil {
+		t.Fatal(err)
+	}
+	if p.HasSpawn {
+		t.Errorf("spawn point was kept")
+	}
+}
+
+func TestSavePlayerWithoutEffects(t *testing.T) {
+	name := writePlayer(t, t.TempDir(), playerSNBT)
+	p, err := LoadPlayer(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ActiveEffects = nil
+	if err = p.Save(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := readTree(t, name).(map[string]interface{})["ActiveEffects"]; ok {
+		t.Errorf("ActiveEffects still saved")
+	}
+	if p, err = LoadPlayer(name); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.ActiveEffects) != 0 {
+		t.Errorf("ActiveEffects = %+v after clearing them", p.ActiveEffects)
+	}
+}
+
+func TestWorldLoadPlayer(t *testing.T) {
+	dir := testWorld(t)
+	writePlayer(t, dir, playerSNBT)
+	w, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := w.LoadPlayer(notchUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.XpLevel != 3 {
+		t.Errorf("XpLevel = %d, want 3", p.XpLevel)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	ints := []int32{110787060, 1156138790, -1514210135, 238594805}
+	uuid, err := UUIDFromInts(ints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uuid != notchUUID {
+		t.Errorf("UUIDFromInts() = %x, want %x", uuid, notchUUID)
+	}
+	if got := UUIDToInts(uuid); !reflect.DeepEqual(got, ints) {
+		t.Errorf("UUIDToInts() = %v, want %v", got, ints)
+	}
+	if _, err = UUIDFromInts(ints[:3]); err == nil {
+		t.Errorf("expected an error converting 3 ints")
+	}
+
+	s := FormatUUID(notchUUID)
+	if s != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Errorf("FormatUUID() = %s", s)
+	}
+	for _, input := range []string{s, "069a79f444e94726a5befca90e38aaf5"} {
+		if got, err := ParseUUID(input); err != nil || got != notchUUID {
+			t.Errorf("ParseUUID(%q) = %x, %v", input, got, err)
+		}
+	}
+	if _, err = ParseUUID("069a79f4"); err == nil {
+		t.Errorf("expected an error parsing a short UUID")
+	}
+
+	var p Player
+	p.SetID(notchUUID)
+	if !reflect.DeepEqual(p.UUID, ints) {
+		t.Errorf("SetID() set %v", p.UUID)
+	}
+}
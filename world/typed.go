@@ -0,0 +1,230 @@
+package world
+
+import (
+	"github.com/junglemc/nbt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// readTyped decodes an NBT file compressed with gzip, zlib or not at all into value,
+// a pointer to a struct, and returns the file as a generic tree as well. Fields tagged
+// optional:"Flag" have their Flag set when the file holds them, and fields tagged
+// unknown:"" collect the keys of their struct that have no field.
+func readTyped(r io.Reader, value interface{}) (map[string]interface{}, error) {
+	dr, _, err := nbt.Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(dr)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if _, err = nbt.Unmarshal(data, reflect.ValueOf(&raw).Elem()); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(value).Elem()
+	if _, err = nbt.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	setPresence(v, raw)
+	setUnknown(v, raw)
+	return raw, nil
+}
+
+// writeTyped merges value, a struct, into raw and writes the result to w compressed
+// with gzip. Keys of raw without a field are kept, except inside maps and lists, which
+// are replaced as a whole; the elements of lists keep theirs through their unknown
+// field. Keys of fields that are not written, such as empty omitempty fields and
+// optional fields whose flag is not set, are removed. Fields whose key raw does not
+// hold are only added when they are not the zero value, so that saving an older or
+// partial file does not add keys it never had.
+func writeTyped(w io.Writer, raw map[string]interface{}, value interface{}) error {
+	var tree map[string]interface{}
+	if _, err := nbt.Unmarshal(nbt.Marshal("", value), reflect.ValueOf(&tree).Elem()); err != nil {
+		return err
+	}
+	addUnknown(reflect.ValueOf(value), tree)
+	dropUnset(reflect.ValueOf(value), raw, tree)
+	nbt.Merge(raw, tree)
+	removeAbsent(reflect.ValueOf(value), raw, tree)
+
+	cw := nbt.Compress(w, nbt.CompressionGzip)
+	if _, err := cw.Write(nbt.Marshal("", raw)); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// nestedFields calls fn for every exported field of the struct v with its tag name and
+// the compound raw holds under that name, if any.
+func nestedFields(v reflect.Value, raw map[string]interface{}, fn func(f reflect.StructField, field reflect.Value, name string, nested map[string]interface{})) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.TrimSuffix(f.Tag.Get("nbt"), ",omitempty")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		nested, _ := raw[name].(map[string]interface{})
+		fn(f, v.Field(i), name, nested)
+	}
+}
+
+func setPresence(v reflect.Value, raw map[string]interface{}) {
+	nestedFields(v, raw, func(f reflect.StructField, field reflect.Value, name string, nested map[string]interface{}) {
+		if flag := f.Tag.Get("optional"); flag != "" {
+			_, present := raw[name]
+			v.FieldByName(flag).SetBool(present)
+		}
+		if field.Kind() == reflect.Struct && nested != nil {
+			setPresence(field, nested)
+		}
+	})
+}
+
+// setUnknown fills the unknown fields of v and of the structs it holds, directly or in
+// slices, with the keys of raw that have no field.
+func setUnknown(v reflect.Value, raw map[string]interface{}) {
+	unknown := make(map[string]interface{})
+	for key, value := range raw {
+		unknown[key] = value
+	}
+
+	nestedFields(v, raw, func(f reflect.StructField, field reflect.Value, name string, nested map[string]interface{}) {
+		delete(unknown, name)
+		switch {
+		case field.Kind() == reflect.Struct && nested != nil:
+			setUnknown(field, nested)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+			list, _ := raw[name].([]interface{})
+			for i := 0; i < field.Len() && i < len(list); i++ {
+				if element, ok := list[i].(map[string]interface{}); ok {
+					setUnknown(field.Index(i), element)
+				}
+			}
+		}
+	})
+	if field := unknownField(v); field.IsValid() && len(unknown) > 0 {
+		field.Set(reflect.ValueOf(unknown))
+	}
+}
+
+// addUnknown adds the keys held by the unknown fields of v and of the structs it
+// holds to tree, the encoded value, unless they belong to a field.
+func addUnknown(v reflect.Value, tree map[string]interface{}) {
+	fields := make(map[string]bool)
+	nestedFields(v, tree, func(f reflect.StructField, field reflect.Value, name string, nested map[string]interface{}) {
+		fields[name] = true
+		switch {
+		case field.Kind() == reflect.Struct && nested != nil:
+			addUnknown(field, nested)
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct:
+			list, _ := tree[name].([]interface{})
+			for i := 0; i < field.Len() && i < len(list); i++ {
+				if element, ok := list[i].(map[string]interface{}); ok {
+					addUnknown(field.Index(i), element)
+				}
+			}
+		}
+	})
+	if field := unknownField(v); field.IsValid() {
+		iter := field.MapRange()
+		for iter.Next() {
+			if key := iter.Key().String(); !fields[key] {
+				tree[key] = iter.Value().Interface()
+			}
+		}
+	}
+}
+
+// unknownField returns the field of the struct v tagged unknown:"", a
+// map[string]interface{} collecting the keys without a field, if there is one.
+func unknownField(v reflect.Value) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if _, ok := f.Tag.Lookup("unknown"); ok && f.Type == reflect.TypeOf(map[string]interface{}{}) {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// dropUnset removes from tree the keys raw does not hold whose field is the zero
+// value. Optional fields count as set when their flag is.
+func dropUnset(v reflect.Value, raw map[string]interface{}, tree map[string]interface{}) {
+	nestedFields(v, raw, func(f reflect.StructField, field reflect.Value, name string, nested map[string]interface{}) {
+		encoded, _ := tree[name].(map[string]interface{})
+		if _, ok := raw[name]; ok {
+			if field.Kind() == reflect.Struct && nested != nil && encoded != nil {
+				dropUnset(field, nested, encoded)
+			}
+			return
+		}
+
+		switch {
+		case f.Tag.Get("optional") != "":
+		case field.IsZero():
+			delete(tree, name)
+		case field.Kind() == reflect.Struct && encoded != nil:
+			dropUnset(field, map[string]interface{}{}, encoded)
+		}
+	})
+}
+
+// removeAbsent removes the keys of raw whose field is not in tree, the encoded value,
+// and replaces the maps of raw with the ones of tree, so that removed entries go.
+func removeAbsent(v reflect.Value, raw map[string]interface{}, tree map[string]interface{}) {
+	nestedFields(v, raw, func(f reflect.StructField, field reflect.Value, name string, nested map[string]interface{}) {
+		encoded, ok := tree[name]
+		switch {
+		case !ok:
+			delete(raw, name)
+		case field.Kind() == reflect.Map:
+			raw[name] = encoded
+		case field.Kind() == reflect.Struct && nested != nil:
+			nestedTree, _ := encoded.(map[string]interface{})
+			removeAbsent(field, nested, nestedTree)
+		}
+	})
+}
+
+// saveFile replaces the file name with data. The new content goes to a temporary file
+// renamed over name, and the previous file is kept as name_old like the game does.
+func saveFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	previous, err := os.ReadFile(name)
+	if err == nil {
+		err = os.WriteFile(name+"_old", previous, 0644)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
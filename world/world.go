@@ -0,0 +1,208 @@
+// Package world reads the save folder of a Java Edition world: its level.dat, the data
+// of its players and the region files of every dimension.
+package world
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/region"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dimension is a dimension of a world and the folder holding its data, relative to the
+// save folder.
+type Dimension struct {
+	ID  string
+	Dir string
+}
+
+var (
+	Overworld = Dimension{ID: "minecraft:overworld", Dir: ""}
+	Nether    = Dimension{ID: "minecraft:the_nether", Dir: "DIM-1"}
+	End       = Dimension{ID: "minecraft:the_end", Dir: "DIM1"}
+)
+
+// Dimensions lists the vanilla dimensions.
+var Dimensions = []Dimension{Overworld, Nether, End}
+
+// Folders of a dimension holding region files: terrain, entities since 1.17, and
+// points of interest such as beds and workstations.
+const (
+	RegionFolder   = "region"
+	EntitiesFolder = "entities"
+	POIFolder      = "poi"
+)
+
+// Folders lists the folders holding region files.
+var Folders = []string{RegionFolder, EntitiesFolder, POIFolder}
+
+// World is an open save folder.
+type World struct {
+	Dir string
+
+	// Level is the content of level.dat.
+	Level *LevelData
+}
+
+// Open opens the save folder dir and reads its level.dat.
+func Open(dir string) (*World, error) {
+	level, err := LoadLevel(filepath.Join(dir, "level.dat"))
+	if err != nil {
+		return nil, err
+	}
+	return &World{Dir: dir, Level: level}, nil
+}
+
+// SaveLevel writes Level back to level.dat.
+func (w *World) SaveLevel() error {
+	return w.Level.Save(filepath.Join(w.Dir, "level.dat"))
+}
+
+// Region is a region file of a world.
+type Region struct {
+	Dimension Dimension
+	Folder    string
+
+	// X and Z are the coordinates of the region.
+	X, Z int
+
+	Path string
+}
+
+// Open opens the region file for reading.
+func (r Region) Open() (*region.File, error) {
+	return region.Open(r.Path)
+}
+
+// Regions lists the region files in the given folders of every dimension, or in all
+// folders if none are given. Missing folders are skipped.
+func (w *World) Regions(folders ...string) ([]Region, error) {
+	if len(folders) == 0 {
+		folders = Folders
+	}
+
+	var regions []Region
+	for _, dim := range Dimensions {
+		for _, folder := range folders {
+			dir := filepath.Join(w.Dir, dim.Dir, folder)
+			names, err := filepath.Glob(filepath.Join(dir, "r.*.*.mca"))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				r := Region{Dimension: dim, Folder: folder, Path: name}
+				if _, err = fmt.Sscanf(filepath.Base(name), "r.%d.%d.mca", &r.X, &r.Z); err != nil {
+					continue
+				}
+				regions = append(regions, r)
+			}
+		}
+	}
+	return regions, nil
+}
+
+// Chunk is a chunk read from a region file.
+type Chunk struct {
+	Region Region
+
+	// X and Z are the coordinates of the chunk in the world.
+	X, Z int
+
+	Timestamp time.Time
+
+	// Data holds the uncompressed NBT data of the chunk.
+	Data []byte
+}
+
+// Decoder returns a Decoder reading the chunk.
+func (c *Chunk) Decoder() *nbt.Decoder {
+	return nbt.NewDecoder(bytes.NewReader(c.Data))
+}
+
+// Unmarshal decodes the chunk into value, like nbt.Unmarshal.
+func (c *Chunk) Unmarshal(value reflect.Value) error {
+	_, err := nbt.Unmarshal(c.Data, value)
+	return err
+}
+
+// ForEachChunk calls fn for every chunk of the regions. With more than one worker,
+// regions are read concurrently and fn is called from several goroutines at once;
+// chunks of the same region are still passed in order. A workers value of zero or less
+// uses one worker per CPU.
+//
+// The first error, from reading a region or returned by fn, stops the iteration and is
+// returned.
+func ForEachChunk(regions []Region, workers int, fn func(c *Chunk) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan Region)
+	done := make(chan struct{})
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				if err := forEachRegionChunk(r, done, fn); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, r := range regions {
+		select {
+		case jobs <- r:
+		case <-done:
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
+}
+
+func forEachRegionChunk(r Region, done <-chan struct{}, fn func(c *Chunk) error) error {
+	f, err := r.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, info := range f.Chunks() {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+
+		data, err := f.ReadChunk(info.X, info.Z)
+		if err != nil {
+			return fmt.Errorf("world: %s: %w", r.Path, err)
+		}
+		if err = fn(&Chunk{Region: r, X: info.X, Z: info.Z, Timestamp: info.Timestamp, Data: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package world
+
+import (
+	"errors"
+	"github.com/junglemc/nbt"
+	"github.com/junglemc/nbt/region"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+type chunkPos struct {
+	XPos int32 `nbt:"xPos"`
+	ZPos int32 `nbt:"zPos"`
+}
+
+func writeNBT(t *testing.T, name string, root interface{}) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := nbt.Compress(f, nbt.CompressionGzip)
+	if _, err = w.Write(nbt.Marshal("", root)); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeChunks(t *testing.T, name string, chunks ...[2]int) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r, err := region.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, c := range chunks {
+		if err = r.Marshal(c[0], c[1], chunkPos{XPos: int32(c[0]), ZPos: int32(c[1])}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testWorld creates a save folder with chunks in every dimension and folder.
+func testWorld(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	writeNBT(t, filepath.Join(dir, "level.dat"), map[string]interface{}{
+		"Data": map[string]interface{}{"LevelName": "Test World"},
+	})
+	writeChunks(t, filepath.Join(dir, "region", "r.0.0.mca"), [2]int{0, 0}, [2]int{1, 0}, [2]int{31, 31})
+	writeChunks(t, filepath.Join(dir, "region", "r.-1.0.mca"), [2]int{-1, 5})
+	writeChunks(t, filepath.Join(dir, "entities", "r.0.0.mca"), [2]int{2, 2})
+	writeChunks(t, filepath.Join(dir, "DIM-1", "region", "r.0.-1.mca"), [2]int{3, -3})
+	writeChunks(t, filepath.Join(dir, "DIM1", "poi", "r.1.1.mca"), [2]int{40, 40})
+	return dir
+}
+
+func TestOpen(t *testing.T) {
+	w, err := Open(testWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Level.Data.LevelName != "Test World" {
+		t.Errorf("LevelName = %q, want %q", w.Level.Data.LevelName, "Test World")
+	}
+
+	if _, err = Open(t.TempDir()); err == nil {
+		t.Errorf("expected an error opening a folder without level.dat")
+	}
+}
+
+func TestRegions(t *testing.T) {
+	w, err := Open(testWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		folders []string
+		want    []Region
+	}{
+		{
+			name: "all",
+			want: []Region{
+				{Dimension: Overworld, Folder: RegionFolder, X: -1, Z: 0},
+				{Dimension: Overworld, Folder: RegionFolder, X: 0, Z: 0},
+				{Dimension: Overworld, Folder: EntitiesFolder, X: 0, Z: 0},
+				{Dimension: Nether, Folder: RegionFolder, X: 0, Z: -1},
+				{Dimension: End, Folder: POIFolder, X: 1, Z: 1},
+			},
+		},
+		{
+			name:    "terrain",
+			folders: []string{RegionFolder},
+			want: []Region{
+				{Dimension: Overworld, Folder: RegionFolder, X: -1, Z: 0},
+				{Dimension: Overworld, Folder: RegionFolder, X: 0, Z: 0},
+				{Dimension: Nether, Folder: RegionFolder, X: 0, Z: -1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.Regions(tt.folders...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range got {
+				got[i].Path = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Regions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEachChunk(t *testing.T) {
+	w, err := Open(testWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := w.Regions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4, 0} {
+		mu := sync.Mutex{}
+		var got [][2]int
+		err = ForEachChunk(regions, workers, func(c *Chunk) error {
+			var pos chunkPos
+			if err := c.Unmarshal(reflect.ValueOf(&pos).Elem()); err != nil {
+				return err
+			}
+			if int(pos.XPos) != c.X || int(pos.ZPos) != c.Z {
+				t.Errorf("chunk %d,%d holds position %d,%d", c.X, c.Z, pos.XPos, pos.ZPos)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, [2]int{c.X, c.Z})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Slice(got, func(i, j int) bool { return got[i][0] < got[j][0] || got[i][0] == got[j][0] && got[i][1] < got[j][1] })
+		want := [][2]int{{-1, 5}, {0, 0}, {1, 0}, {2, 2}, {3, -3}, {31, 31}, {40, 40}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers visited %v, want %v", workers, got, want)
+		}
+	}
+}
+
+func TestForEachChunkError(t *testing.T) {
+	w, err := Open(testWorld(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := w.Regions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = ForEachChunk(regions, 1, func(c *Chunk) error {
+		calls++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("ForEachChunk() error = %v, want %v", err, stop)
+	}
+	if calls != 1 {
+		t.Errorf("fn was called %d times after failing", calls)
+	}
+}
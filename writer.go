@@ -0,0 +1,194 @@
+package nbt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+)
+
+type writerFrame struct {
+	list     bool
+	elemType TagType
+	length   int
+	written  int
+}
+
+// Writer writes named binary tags incrementally, without building Go values first.
+// Every BeginCompound and BeginList must be closed with End. Elements of a list are
+// written with an empty name and must match the element type and count declared in
+// BeginList.
+type Writer struct {
+	w     io.Writer
+	stack []writerFrame
+	err   error
+}
+
+// NewWriter returns a writer writing tags to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// BeginCompound opens a compound tag. The tags written until the matching End are
+// its entries.
+func (w *Writer) BeginCompound(name string) error {
+	if err := w.header(TagCompound, name); err != nil {
+		return err
+	}
+	w.stack = append(w.stack, writerFrame{})
+	return nil
+}
+
+// BeginList opens a list of n elements of elemType. Exactly n elements must be
+// written before the matching End.
+func (w *Writer) BeginList(name string, elemType TagType, n int) error {
+	if err := w.checkLength("list", n); err != nil {
+		return err
+	}
+	if err := w.header(TagList, name); err != nil {
+		return err
+	}
+	if err := w.write(append(writeTagType(elemType), writeInt32(int32(n))...)); err != nil {
+		return err
+	}
+	w.stack = append(w.stack, writerFrame{list: true, elemType: elemType, length: n})
+	return nil
+}
+
+// End closes the innermost open compound or list. Closing a list before all declared
+// elements have been written is an error.
+func (w *Writer) End() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.stack) == 0 {
+		return w.fail(errors.New("End called without an open compound or list"))
+	}
+
+	top := w.stack[len(w.stack)-1]
+	w.stack = w.stack[:len(w.stack)-1]
+	if top.list {
+		if top.written != top.length {
+			return w.fail(fmt.Errorf("list declared with %d elements, %d written", top.length, top.written))
+		}
+		return nil
+	}
+	return w.write(writeTagType(TagEnd))
+}
+
+// Byte writes a TAG_Byte.
+func (w *Writer) Byte(name string, v byte) error {
+	return w.value(TagByte, name, writeByte(v))
+}
+
+// Short writes a TAG_Short.
+func (w *Writer) Short(name string, v int16) error {
+	return w.value(TagShort, name, writeInt16(v))
+}
+
+// Int writes a TAG_Int.
+func (w *Writer) Int(name string, v int32) error {
+	return w.value(TagInt, name, writeInt32(v))
+}
+
+// Long writes a TAG_Long.
+func (w *Writer) Long(name string, v int64) error {
+	return w.value(TagLong, name, writeInt64(v))
+}
+
+// Float writes a TAG_Float.
+func (w *Writer) Float(name string, v float32) error {
+	return w.value(TagFloat, name, writeFloat32(v))
+}
+
+// Double writes a TAG_Double.
+func (w *Writer) Double(name string, v float64) error {
+	return w.value(TagDouble, name, writeFloat64(v))
+}
+
+// String writes a TAG_String.
+func (w *Writer) String(name string, v string) error {
+	return w.value(TagString, name, writeString(v))
+}
+
+// ByteArray writes a TAG_Byte_Array.
+func (w *Writer) ByteArray(name string, v []byte) error {
+	if err := w.checkLength("array", len(v)); err != nil {
+		return err
+	}
+	return w.value(TagByteArray, name, writeByteSlice(v))
+}
+
+// IntArray writes a TAG_Int_Array.
+func (w *Writer) IntArray(name string, v []int32) error {
+	if err := w.checkLength("array", len(v)); err != nil {
+		return err
+	}
+	return w.value(TagIntArray, name, writeInt32Slice(reflect.ValueOf(v)))
+}
+
+// LongArray writes a TAG_Long_Array.
+func (w *Writer) LongArray(name string, v []int64) error {
+	if err := w.checkLength("array", len(v)); err != nil {
+		return err
+	}
+	return w.value(TagLongArray, name, writeInt64Slice(reflect.ValueOf(v)))
+}
+
+// checkLength returns an error if a list or array length cannot be encoded as the
+// int32 that precedes its elements.
+func (w *Writer) checkLength(kind string, n int) error {
+	if w.err != nil {
+		return w.err
+	}
+	if n < 0 {
+		return w.fail(fmt.Errorf("negative %s length", kind))
+	}
+	if int64(n) > math.MaxInt32 {
+		return w.fail(fmt.Errorf("%s length %d does not fit in an int32", kind, n))
+	}
+	return nil
+}
+
+func (w *Writer) value(tagType TagType, name string, payload []byte) error {
+	if err := w.header(tagType, name); err != nil {
+		return err
+	}
+	return w.write(payload)
+}
+
+// header writes the type and name of a tag, or validates it against the enclosing
+// list, which carries neither.
+func (w *Writer) header(tagType TagType, name string) error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.stack) == 0 || !w.stack[len(w.stack)-1].list {
+		return w.write(append(writeTagType(tagType), writeString(name)...))
+	}
+
+	top := &w.stack[len(w.stack)-1]
+	if tagType != top.elemType {
+		return w.fail(fmt.Errorf("cannot write %v into a list of %v", tagType, top.elemType))
+	}
+	if top.written >= top.length {
+		return w.fail(fmt.Errorf("list declared with %d elements, cannot write more", top.length))
+	}
+	top.written++
+	return nil
+}
+
+func (w *Writer) write(b []byte) error {
+	if _, err := w.w.Write(b); err != nil {
+		return w.fail(err)
+	}
+	return nil
+}
+
+func (w *Writer) fail(err error) error {
+	if w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
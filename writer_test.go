@@ -0,0 +1,120 @@
+package nbt
+
+import (
+	"bytes"
+	"github.com/junglemc/nbt/test"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func(w *Writer) error
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name: "unnamed root compound tag",
+			write: func(w *Writer) error {
+				_ = w.BeginCompound("")
+				_ = w.Byte("ByteTag", 0xFF)
+				_ = w.String("StringTag", "hello, world")
+				return w.End()
+			},
+			want: test.UnnamedRootCompoundBytes,
+		},
+		{
+			name: "bananrama",
+			write: func(w *Writer) error {
+				_ = w.BeginCompound("hello world")
+				_ = w.String("name", "Bananrama")
+				return w.End()
+			},
+			want: test.BananramaBytes,
+		},
+		{
+			name: "list element type mismatch",
+			write: func(w *Writer) error {
+				_ = w.BeginCompound("")
+				_ = w.BeginList("list", TagInt, 1)
+				return w.Long("", 1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many list elements",
+			write: func(w *Writer) error {
+				_ = w.BeginCompound("")
+				_ = w.BeginList("list", TagInt, 1)
+				_ = w.Int("", 1)
+				return w.Int("", 2)
+			},
+			wantErr: true,
+		},
+		{
+			name: "too few list elements",
+			write: func(w *Writer) error {
+				_ = w.BeginCompound("")
+				_ = w.BeginList("list", TagInt, 2)
+				_ = w.Int("", 1)
+				return w.End()
+			},
+			wantErr: true,
+		},
+		{
+			name: "list length beyond int32",
+			write: func(w *Writer) error {
+				_ = w.BeginCompound("")
+				return w.BeginList("list", TagInt, math.MaxInt32+1)
+			},
+			wantErr: true,
+		},
+		{
+			name: "unbalanced end",
+			write: func(w *Writer) error {
+				return w.End()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := tt.write(NewWriter(buf))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("got:\n[% 2x]\nwant:\n[% 2x]", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterList(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	_ = w.BeginCompound("")
+	_ = w.BeginList("listTest (long)", TagLong, 5)
+	for i := int64(11); i <= 15; i++ {
+		_ = w.Long("", i)
+	}
+	_ = w.End()
+	if err := w.End(); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+
+	var got struct {
+		ListTest []int64 `nbt:"listTest (long)"`
+	}
+	if _, err := Unmarshal(buf.Bytes(), reflect.ValueOf(&got).Elem()); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(got.ListTest) != 5 || got.ListTest[4] != 15 {
+		t.Errorf("got %v", got.ListTest)
+	}
+}